@@ -0,0 +1,195 @@
+package cephfs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Option configures an Fs created by NewCephFS or ToAferoFS.
+type Option func(*Fs)
+
+// DefaultSortMemEntries is the number of names WithSortedReaddir keeps in
+// memory when it is given no limit.
+const DefaultSortMemEntries = 64 * 1024
+
+// Whence values for Seek that find data and holes in sparse files, with the
+// values Linux uses for lseek.
+const (
+	SeekData = 3
+	SeekHole = 4
+)
+
+// DiskUsage describes the capacity available to a path.
+type DiskUsage struct {
+	// TotalBytes, FreeBytes and ClusterAvailableBytes are the capacity of
+	// the filesystem as reported by statfs.
+	TotalBytes            uint64 `json:"totalBytes"`
+	FreeBytes             uint64 `json:"freeBytes"`
+	ClusterAvailableBytes uint64 `json:"clusterAvailableBytes"`
+	TotalFiles            uint64 `json:"totalFiles"`
+	FreeFiles             uint64 `json:"freeFiles"`
+
+	// QuotaRoot is the nearest directory at or above the path that has a
+	// quota set, empty if there is none.
+	QuotaRoot     string `json:"quotaRoot,omitempty"`
+	QuotaMaxBytes uint64 `json:"quotaMaxBytes,omitempty"`
+	QuotaMaxFiles uint64 `json:"quotaMaxFiles,omitempty"`
+	// QuotaUsedBytes and QuotaUsedFiles are the recursive usage of
+	// QuotaRoot.
+	QuotaUsedBytes uint64 `json:"quotaUsedBytes,omitempty"`
+	QuotaUsedFiles uint64 `json:"quotaUsedFiles,omitempty"`
+
+	// AvailableBytes is the space that can actually be written at the
+	// path, the smaller of the cluster's available space and what is left
+	// of the quota.
+	AvailableBytes uint64 `json:"availableBytes"`
+	// AvailableFiles is the number of files that can still be created at
+	// the path, the smaller of FreeFiles and what is left of the quota.
+	AvailableFiles uint64 `json:"availableFiles"`
+}
+
+// PinNone is the rank that removes an export pin, letting the directory
+// inherit the pin of its parent.
+const PinNone = -1
+
+// DirPin is the MDS pinning policy set on a directory.
+type DirPin struct {
+	// Rank is the MDS rank the directory is pinned to, PinNone if unset.
+	Rank int
+	// Distributed spreads the immediate children across all ranks.
+	Distributed bool
+	// Random is the probability that a descendant directory is pinned to
+	// a random rank, 0 if unset.
+	Random float64
+}
+
+const (
+	defaultCopyChunkSize   = 4 << 20
+	defaultCopyParallelism = 4
+)
+
+// CopyOptions controls how CopyFile and CopyTree copy data and which
+// attributes of the source are carried over to the destination. A nil
+// *CopyOptions copies the data only.
+type CopyOptions struct {
+	// ChunkSize is the size of each ranged read/write. Defaults to 4MiB.
+	ChunkSize int64
+	// Parallelism is the number of ranges copied concurrently. Defaults to 4.
+	Parallelism int
+
+	PreserveMode  bool
+	PreserveOwner bool
+	// PreserveTimes copies the access and modification times, which are
+	// set through the open destination, see File.Chtimes.
+	PreserveTimes  bool
+	PreserveXattrs bool
+	// PreserveLayout copies the ceph.file.layout of the source. The layout
+	// is applied before any data is written, as ceph requires.
+	PreserveLayout bool
+
+	// Sparse skips writing ranges that read back as all zeros, leaving
+	// holes in the destination instead of allocating them.
+	Sparse bool
+}
+
+func (opts *CopyOptions) chunkSize() int64 {
+	if opts == nil || opts.ChunkSize <= 0 {
+		return defaultCopyChunkSize
+	}
+	return opts.ChunkSize
+}
+
+func (opts *CopyOptions) parallelism() int {
+	if opts == nil || opts.Parallelism <= 0 {
+		return defaultCopyParallelism
+	}
+	return opts.Parallelism
+}
+
+// RemoveAllOptions configures RemoveAllWithOptions.
+type RemoveAllOptions struct {
+	// Parallelism is the number of directories listed and emptied, and
+	// later removed, concurrently. 1 if 0 or less.
+	Parallelism int
+	// Progress, if set, is called after every removed entry with its path
+	// and the number of entries removed so far. Calls are serialized.
+	Progress func(path string, removed int64)
+}
+
+// RemoveAllError is returned by RemoveAll when some paths could not be
+// removed. Everything else has been removed.
+type RemoveAllError struct {
+	// Errs holds an *os.PathError for every path that was not removed,
+	// including the directories that still contain such a path.
+	Errs []error
+}
+
+func (e *RemoveAllError) Error() string {
+	msgs := make([]string, 0, len(e.Errs))
+	for _, err := range e.Errs {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("failed to remove %d paths: %s", len(e.Errs), strings.Join(msgs, "; "))
+}
+
+func (e *RemoveAllError) Unwrap() []error {
+	return e.Errs
+}
+
+// AtomicOptions controls which attributes of an existing target are carried
+// over to the file that replaces it on Commit.
+type AtomicOptions struct {
+	// CopyMode applies the permission bits of the existing target instead of
+	// the perm passed to CreateAtomic.
+	CopyMode bool
+	// CopyXattrs copies the extended attributes of the existing target.
+	CopyXattrs bool
+}
+
+// Health describes the state of an Fs as seen by Ping.
+type Health struct {
+	Healthy bool `json:"healthy"`
+	Mounted bool `json:"mounted"`
+	// ClientID is the ceph client name, e.g. client.admin.
+	ClientID string `json:"clientId"`
+	// FilesystemID is the fscid of the mounted filesystem.
+	FilesystemID int64         `json:"filesystemId"`
+	Latency      time.Duration `json:"latencyNs"`
+	Error        string        `json:"error,omitempty"`
+	CheckedAt    time.Time     `json:"checkedAt"`
+}
+
+// RemountEvent describes an attempt to re-create the mount of an Fs after
+// its client was evicted or blocklisted by the cluster.
+type RemountEvent struct {
+	// Cause is the error that revealed the eviction.
+	Cause error
+	// Err is nil if the mount was re-created. On failure the next
+	// eviction error triggers another attempt.
+	Err  error
+	Time time.Time
+}
+
+// HealthHandler returns an http.Handler that serves Health as JSON, with
+// status 200 when healthy and 503 otherwise. Each check is bounded by
+// timeout, suitable for a kubernetes readiness probe.
+func (fs *Fs) HealthHandler(timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		health := fs.Health(ctx)
+
+		w.Header().Set("Content-Type", "application/json")
+		if health.Healthy {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(health)
+	})
+}
@@ -0,0 +1,243 @@
+//go:build cgo && !nocephfs
+
+package cephfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	gocephfs "github.com/ceph/go-ceph/cephfs"
+)
+
+const atomicTempMarker = ".atomic-"
+
+// AtomicFile is a file that is written to a hidden temporary file in the
+// directory of its target and only becomes visible at the target path when
+// Commit renames it into place. Readers on other clients see either the old
+// or the new content, never a partial write.
+type AtomicFile struct {
+	*File
+	target string
+	opts   AtomicOptions
+	done   bool
+}
+
+func atomicTempPath(target string) string {
+	dir, base := filepath.Split(target)
+	suffix := strconv.FormatUint(rand.Uint64(), 36)
+	return dir + "." + base + atomicTempMarker + suffix
+}
+
+// CreateAtomic creates an AtomicFile that replaces path on Commit. opts may
+// be nil.
+func (fs *Fs) CreateAtomic(path string, perm os.FileMode, opts *AtomicOptions) (_ *AtomicFile, err error) {
+	mount := fs.getMount()
+	defer fs.detectEviction(mount, &err)
+
+	if opts == nil {
+		opts = &AtomicOptions{}
+	}
+	path, dirOnly, err := normPath("create", path)
+	if err != nil {
+		return nil, err
+	}
+	if dirOnly {
+		return nil, &os.PathError{Op: "create", Path: path + "/", Err: syscall.EISDIR}
+	}
+
+	tmp := atomicTempPath(path)
+	cfile, err := mount.Open(tmp, os.O_RDWR|os.O_CREATE|os.O_EXCL, uint32(perm.Perm()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file for %s: %w", path, convertErr(err))
+	}
+
+	fs.holdMount(mount)
+	return &AtomicFile{
+		File:   &File{fs: fs, mount: mount, path: tmp, file: cfile, flag: os.O_RDWR},
+		target: path,
+		opts:   *opts,
+	}, nil
+}
+
+// Target returns the path the file is renamed to on Commit.
+func (af *AtomicFile) Target() string {
+	return af.target
+}
+
+// Commit flushes the written data to stable storage, applies the requested
+// attributes of the existing target and renames the temp file over the
+// target. The temp file is removed if any step fails.
+func (af *AtomicFile) Commit() error {
+	if af.done {
+		return ErrAtomicFileDone
+	}
+	af.done = true
+	if af.stale() {
+		af.File.Close()
+		return ErrStaleFile
+	}
+
+	// the mount is used after the file is closed
+	af.fs.holdMount(af.mount)
+	defer af.fs.putMount(af.mount)
+	if err := af.commit(); err != nil {
+		af.File.Close()
+		af.mount.Unlink(af.path)
+		return err
+	}
+	return nil
+}
+
+func (af *AtomicFile) commit() (err error) {
+	defer af.detectEviction(&err)
+
+	if err := af.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", af.path, err)
+	}
+
+	if af.opts.CopyMode || af.opts.CopyXattrs {
+		if err := af.copyTargetAttrs(); err != nil {
+			return err
+		}
+	}
+
+	if err := af.File.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", af.path, err)
+	}
+
+	if err := af.mount.Rename(af.path, af.target); err != nil {
+		return fmt.Errorf("failed to rename %s to %s: %w", af.path, af.target, err)
+	}
+	return nil
+}
+
+func (af *AtomicFile) copyTargetAttrs() error {
+	target, err := af.mount.Open(af.target, os.O_RDONLY, 0)
+	if err != nil {
+		if errors.Is(err, gocephfs.ErrNotExist) {
+			// nothing to copy from when creating a new file
+			return nil
+		}
+		return fmt.Errorf("failed to open %s: %w", af.target, err)
+	}
+	defer target.Close()
+
+	if af.opts.CopyXattrs {
+		if err := copyXattrs(target, af.file); err != nil {
+			return err
+		}
+	}
+
+	if af.opts.CopyMode {
+		stat, err := target.Fstatx(gocephfs.StatxMode, 0)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", af.target, err)
+		}
+		if err := af.file.Fchmod(uint32(stat.Mode & 07777)); err != nil {
+			return fmt.Errorf("failed to copy mode: %w", err)
+		}
+	}
+	return nil
+}
+
+// Abort closes and removes the temp file, leaving the target untouched.
+// Abort after Commit is a no-op, so it is safe to defer.
+func (af *AtomicFile) Abort() error {
+	if af.done {
+		return nil
+	}
+	af.done = true
+	if af.stale() {
+		af.File.Close()
+		return ErrStaleFile
+	}
+
+	af.fs.holdMount(af.mount)
+	defer af.fs.putMount(af.mount)
+	closeErr := af.File.Close()
+	if err := af.mount.Unlink(af.path); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", af.path, convertErr(err))
+	}
+	return closeErr
+}
+
+// WriteFileAtomic writes data to path atomically, see AtomicFile.
+func (fs *Fs) WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	af, err := fs.CreateAtomic(path, perm, nil)
+	if err != nil {
+		return err
+	}
+	defer af.Abort()
+
+	if _, err := af.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", af.path, err)
+	}
+	return af.Commit()
+}
+
+// WriteReaderAtomic copies r to path atomically, see AtomicFile.
+func (fs *Fs) WriteReaderAtomic(path string, r io.Reader, perm os.FileMode) error {
+	af, err := fs.CreateAtomic(path, perm, nil)
+	if err != nil {
+		return err
+	}
+	defer af.Abort()
+
+	if _, err := io.Copy(af, r); err != nil {
+		return fmt.Errorf("failed to write %s: %w", af.path, err)
+	}
+	return af.Commit()
+}
+
+// RemoveStaleAtomicFiles removes temp files left in dir by AtomicFiles that
+// were neither committed nor aborted, e.g. because the writer crashed. Only
+// temp files not modified for at least age are removed, so writers that are
+// still active are not disturbed. It returns the paths it removed.
+func (fs *Fs) RemoveStaleAtomicFiles(dir string, age time.Duration) (_ []string, err error) {
+	mount := fs.getMount()
+	defer fs.detectEviction(mount, &err)
+
+	if dir, _, err = normPath("open", dir); err != nil {
+		return nil, err
+	}
+	cdir, err := mount.OpenDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dir %s: %w", dir, convertErr(err))
+	}
+	defer cdir.Close()
+
+	cutoff := time.Now().Add(-age)
+	removed := make([]string, 0)
+
+	for {
+		de, err := cdir.ReadDirPlus(gocephfs.StatxMtime, gocephfs.AtSymlinkNofollow)
+		if err != nil {
+			return removed, fmt.Errorf("failed to readdir: %w", err)
+		}
+		if de == nil {
+			return removed, nil
+		}
+
+		name := de.Name()
+		if !strings.HasPrefix(name, ".") || !strings.Contains(name, atomicTempMarker) {
+			continue
+		}
+		if de.DType() != gocephfs.DTypeReg || timespecToTime(de.Statx().Mtime).After(cutoff) {
+			continue
+		}
+
+		fullPath := filepath.Join(dir, name)
+		if err := mount.Unlink(fullPath); err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", fullPath, err)
+		}
+		removed = append(removed, fullPath)
+	}
+}
@@ -0,0 +1,90 @@
+//go:build cgo && !nocephfs
+
+package cephfs_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"github.com/spf13/afero"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		tDir := testDir(fs)
+		path := filepath.Join(tDir, testName)
+
+		if err := afero.WriteFile(fs, path, []byte("old"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := cfs.WriteFileAtomic(path, []byte("new"), 0o644); err != nil {
+			t.Fatalf("%v: WriteFileAtomic failed: %v", fs.Name(), err)
+		}
+
+		got, err := afero.ReadFile(fs, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "new" {
+			t.Errorf("%v: content = %q, want %q", fs.Name(), got, "new")
+		}
+
+		names, err := readDirNames(fs, tDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(names) != 1 || names[0] != testName {
+			t.Errorf("%v: temp files left behind: %v", fs.Name(), names)
+		}
+	}
+}
+
+func TestAtomicFileAbort(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		tDir := testDir(fs)
+		path := filepath.Join(tDir, testName)
+
+		af, err := cfs.CreateAtomic(path, 0o644, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(filepath.Base(af.Name()), ".") {
+			t.Errorf("%v: temp file %s is not hidden", fs.Name(), af.Name())
+		}
+		af.WriteString("partial")
+
+		if _, err := fs.Stat(path); err == nil {
+			t.Errorf("%v: target visible before commit", fs.Name())
+		}
+
+		if err := af.Abort(); err != nil {
+			t.Errorf("%v: Abort failed: %v", fs.Name(), err)
+		}
+		if err := af.Commit(); !errors.Is(err, cephfs.ErrAtomicFileDone) {
+			t.Errorf("%v: Commit after Abort = %v, want %v", fs.Name(), err, cephfs.ErrAtomicFileDone)
+		}
+
+		names, err := readDirNames(fs, tDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(names) != 0 {
+			t.Errorf("%v: files left behind after abort: %v", fs.Name(), names)
+		}
+	}
+}
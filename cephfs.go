@@ -1,12 +1,16 @@
+//go:build cgo && !nocephfs
+
 package cephfs
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -15,7 +19,33 @@ import (
 )
 
 type Fs struct {
+	mu    sync.RWMutex
 	mount *gocephfs.MountInfo
+	// args are the options the mount was created with, nil if the mount
+	// was provided by the caller. Only mounts created from args can be
+	// re-created after an eviction.
+	args      *cephArgs
+	unmounted bool
+	// cwd is the working directory set with Chdir, "" if it was never set.
+	cwd string
+
+	syncOnClose bool
+	// sortMemEntries is set by WithSortedReaddir, 0 if Readdir is unsorted.
+	sortMemEntries int
+	// readdirMask is the statx mask Readdir stats entries with, see
+	// WithReaddirStatx.
+	readdirMask gocephfs.StatxMask
+	lazyReaddir bool
+
+	onRemount func(RemountEvent)
+	// remounting is set while remount creates a new mount without holding
+	// mu, so concurrent evictions only remount once.
+	remounting bool
+	// mountUsers counts the operations and open files using each mount,
+	// see getMount.
+	mountUsers map[*gocephfs.MountInfo]int
+	// tracer is set by WithTracer, nil if operations are not traced.
+	tracer Tracer
 }
 
 type cephArgs struct {
@@ -51,9 +81,22 @@ func getCephArgs() cephArgs {
 	return myArgs
 }
 
-func NewCephFS() (*Fs, error) {
+func NewCephFS(opts ...Option) (*Fs, error) {
 	args := getCephArgs()
 
+	mount, err := createMount(args)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := &Fs{mount: mount, args: &args, readdirMask: gocephfs.StatxBasicStats}
+	for _, opt := range opts {
+		opt(fs)
+	}
+	return fs, nil
+}
+
+func createMount(args cephArgs) (*gocephfs.MountInfo, error) {
 	mountId, _ := strings.CutPrefix(args.Name, "client.")
 
 	mount, err := gocephfs.CreateMountWithId(mountId)
@@ -75,11 +118,15 @@ func NewCephFS() (*Fs, error) {
 		return nil, fmt.Errorf("failed to mount cephfs: %w", err)
 	}
 
-	return &Fs{mount}, nil
+	return mount, nil
 }
 
-func ToAferoFS(cephfsys *gocephfs.MountInfo) *Fs {
-	return &Fs{cephfsys}
+func ToAferoFS(cephfsys *gocephfs.MountInfo, opts ...Option) *Fs {
+	fs := &Fs{mount: cephfsys, readdirMask: gocephfs.StatxBasicStats}
+	for _, opt := range opts {
+		opt(fs)
+	}
+	return fs
 }
 
 func convertErr(err error) error {
@@ -97,7 +144,23 @@ func convertErr(err error) error {
 
 // filesystem struct
 
+// Unmount unmounts and releases the mount of fs. Operations still running
+// and files still open keep using the mount, which is then released by the
+// last of them to finish, without reporting errors. fs must not be used
+// for new operations after Unmount. Calling Unmount again does nothing.
 func (fs *Fs) Unmount() error {
+	fs.mu.Lock()
+	if fs.unmounted {
+		fs.mu.Unlock()
+		return nil
+	}
+	fs.unmounted = true
+	inUse := fs.mountUsers[fs.mount] > 0
+	fs.mu.Unlock()
+	if inUse {
+		return nil
+	}
+
 	if err := fs.mount.Unmount(); err != nil {
 		return fmt.Errorf("failed to unmount cephfs: %w", err)
 	}
@@ -110,17 +173,19 @@ func (fs *Fs) Unmount() error {
 // Create creates a file in the filesystem, returning the file and an
 // error, if any happens.
 func (fs *Fs) Create(path string) (afero.File, error) {
-	cfile, err := fs.mount.Open(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
-	if err != nil {
-		return nil, err
-	}
-	return &File{fs.mount, path, cfile, nil}, nil
+	return fs.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
 }
 
 // Mkdir creates a directory in the filesystem, return an error if any
 // happens.
-func (fs *Fs) Mkdir(path string, perm os.FileMode) error {
-	if err := fs.mount.MakeDir(path, uint32(perm.Perm())); err != nil {
+func (fs *Fs) Mkdir(path string, perm os.FileMode) (err error) {
+	mount := fs.getMount()
+	defer fs.detectEviction(mount, &err)
+
+	if path, _, err = normPath("mkdir", path); err != nil {
+		return err
+	}
+	if err := mount.MakeDir(path, uint32(perm.Perm())); err != nil {
 		err = convertErr(err)
 		return fmt.Errorf("failed to create directory %s: %w", path, err)
 	}
@@ -129,8 +194,14 @@ func (fs *Fs) Mkdir(path string, perm os.FileMode) error {
 
 // MkdirAll creates a directory path and all parents that does not exist
 // yet.
-func (fs *Fs) MkdirAll(path string, perm os.FileMode) error {
-	return fs.mount.MakeDirs(path, uint32(perm.Perm()))
+func (fs *Fs) MkdirAll(path string, perm os.FileMode) (err error) {
+	mount := fs.getMount()
+	defer fs.detectEviction(mount, &err)
+
+	if path, _, err = normPath("mkdir", path); err != nil {
+		return err
+	}
+	return mount.MakeDirs(path, uint32(perm.Perm()))
 }
 
 // Open opens a file, returning it or an error, if any happens.
@@ -140,31 +211,68 @@ func (fs *Fs) Open(path string) (afero.File, error) {
 
 // OpenFile opens a file using the given flags and the given mode.
 func (fs *Fs) OpenFile(path string, flag int, perm os.FileMode) (afero.File, error) {
-	cfile, err := fs.mount.Open(path, flag, uint32(perm.Perm()))
+	f, err := fs.openFile(context.Background(), path, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (fs *Fs) openFile(ctx context.Context, path string, flag int, perm os.FileMode) (_ *File, err error) {
+	_, span := fs.startSpan(ctx, "open", Attribute{AttrPath, path}, Attribute{AttrFlags, int64(flag)})
+	defer func() { span.end(err) }()
+	mount := fs.getMount()
+	defer fs.detectEviction(mount, &err)
+
+	path, dirOnly, err := normPath("open", path)
 	if err != nil {
-		return nil, convertErr(err)
+		return nil, err
+	}
+
+	// O_SYNC and O_DSYNC are applied by File itself, see syncWrite
+	cfile, err := mount.Open(path, flag&^syncFlags, uint32(perm.Perm()))
+	if err != nil {
+		return nil, pathErr("open", path, err)
 	}
 
 	info, err := cfile.Fstatx(gocephfs.StatxBasicStats, 0)
 	if err != nil {
-		return nil, err
+		cfile.Close()
+		return nil, pathErr("open", path, err)
+	}
+	if dirOnly && !toFileMode(info.Mode).IsDir() {
+		cfile.Close()
+		return nil, &os.PathError{Op: "open", Path: path + "/", Err: syscall.ENOTDIR}
 	}
 
 	if toFileMode(info.Mode).IsDir() {
-		dir, err := fs.mount.OpenDir(path)
+		dir, err := mount.OpenDir(path)
 		if err != nil {
-			return nil, convertErr(err)
+			cfile.Close()
+			return nil, pathErr("open", path, err)
 		}
-		return &File{fs.mount, path, cfile, dir}, nil
+		fs.holdMount(mount)
+		return &File{fs: fs, mount: mount, path: path, file: cfile, dir: dir, flag: flag}, nil
 	}
 
-	return &File{fs.mount, path, cfile, nil}, nil
+	fs.holdMount(mount)
+	return &File{fs: fs, mount: mount, path: path, file: cfile, flag: flag}, nil
 }
 
 // Remove removes a file identified by name, returning an error, if any
 // happens.
-func (fs *Fs) Remove(path string) error {
-	return convertErr(fs.mount.Unlink(path))
+func (fs *Fs) Remove(path string) (err error) {
+	mount := fs.getMount()
+	defer fs.detectEviction(mount, &err)
+
+	path, dirOnly, err := normPath("remove", path)
+	if err != nil {
+		return err
+	}
+	if dirOnly {
+		return convertErr(mount.RemoveDir(path))
+	}
+	return convertErr(mount.Unlink(path))
 }
 
 func forDirItem(dir *gocephfs.Directory, callback func(*gocephfs.DirEntry) error) error {
@@ -186,77 +294,45 @@ func forDirItem(dir *gocephfs.Directory, callback func(*gocephfs.DirEntry) error
 	return nil
 }
 
-// RemoveAll removes a directory path and any children it contains. It
-// does not fail if the path does not exist (return nil).
-func (fs *Fs) RemoveAll(path string) error {
-
-	stat, err := fs.Stat(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return err
-	}
-
-	if !stat.IsDir() {
-		err := fs.Remove(path)
-		if err != nil {
-			return fmt.Errorf("'RemoveAll' failed to remove file at path %s: %w", path, err)
-		}
-		return nil
-	}
-
-	dir, err := fs.mount.OpenDir(path)
-	if err != nil {
-		return fmt.Errorf("failed to open dir %s: %w", path, err)
-	}
-	defer dir.Close()
-
-	err = forDirItem(dir, func(de *gocephfs.DirEntry) error {
-		if name := de.Name(); name == "." || name == ".." {
-			return nil
-		}
+// Rename renames a file, replacing newPath if it exists. Errors are
+// *os.LinkError values, see RenameNoReplace and MoveAcross for variants.
+func (fs *Fs) Rename(oldPath, newPath string) error {
+	return fs.rename(context.Background(), oldPath, newPath)
+}
 
-		fullPath := path + "/" + de.Name()
+func (fs *Fs) rename(ctx context.Context, oldPath, newPath string) (err error) {
+	_, span := fs.startSpan(ctx, "rename", Attribute{AttrPath, oldPath}, Attribute{AttrNewPath, newPath})
+	defer func() { span.end(err) }()
+	mount := fs.getMount()
+	defer fs.detectEviction(mount, &err)
 
-		switch de.DType() {
-		case gocephfs.DTypeDir:
-			if err := fs.RemoveAll(fullPath); err != nil {
-				return err
-			}
-			return nil
-		case gocephfs.DTypeLnk, gocephfs.DTypeReg:
-			if err := fs.mount.Unlink(fullPath); err != nil {
-				return fmt.Errorf("failed to remove file %s: %w", fullPath, err)
-			}
-			return nil
-		default:
-			if err := fs.mount.Unlink(fullPath); err != nil {
-				return fmt.Errorf("failed to remove unknown entry: %w", err)
-			}
-			return nil
-		}
-	})
-	if err != nil {
+	if oldPath, _, err = normPath("rename", oldPath); err != nil {
 		return err
 	}
-
-	if err := fs.mount.RemoveDir(path); err != nil {
+	if newPath, _, err = normPath("rename", newPath); err != nil {
 		return err
 	}
-
-	return nil
-}
-
-// Rename renames a file.
-func (fs *Fs) Rename(oldPath, newPath string) error {
-	return fs.mount.Rename(oldPath, newPath)
+	return renameErr(oldPath, newPath, mount.Rename(oldPath, newPath))
 }
 
 // Stat returns a FileInfo describing the named file, or an error, if any
 // happens.
 func (fs *Fs) Stat(path string) (os.FileInfo, error) {
-	stat, err := fs.mount.Statx(path, gocephfs.StatxBasicStats, 0)
+	return fs.stat(context.Background(), path)
+}
+
+func (fs *Fs) stat(ctx context.Context, path string) (_ os.FileInfo, err error) {
+	_, span := fs.startSpan(ctx, "stat", Attribute{AttrPath, path})
+	defer func() { span.end(err) }()
+	mount := fs.getMount()
+	defer fs.detectEviction(mount, &err)
+
+	path, dirOnly, err := normPath("stat", path)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := mount.Statx(path, gocephfs.StatxBasicStats, 0)
 	if err != nil {
 		// the webdav library checks for the os.ErrNotExist error
 		// without this fix, the rename function doesn't work properly
@@ -265,6 +341,9 @@ func (fs *Fs) Stat(path string) (os.FileInfo, error) {
 		}
 		return nil, err
 	}
+	if dirOnly && !toFileMode(stat.Mode).IsDir() {
+		return nil, &os.PathError{Op: "stat", Path: path + "/", Err: syscall.ENOTDIR}
+	}
 	return &FileInfo{stat: stat, path: path}, nil
 }
 
@@ -274,27 +353,53 @@ func (fs *Fs) Name() string {
 }
 
 // Chmod changes the mode of the named file to mode.
-func (fs *Fs) Chmod(path string, mode os.FileMode) error {
-	return fs.mount.Chmod(path, uint32(mode.Perm()))
+func (fs *Fs) Chmod(path string, mode os.FileMode) (err error) {
+	mount := fs.getMount()
+	defer fs.detectEviction(mount, &err)
+
+	if path, _, err = normPath("chmod", path); err != nil {
+		return err
+	}
+	return mount.Chmod(path, uint32(mode.Perm()))
 }
 
 // Chown changes the uid and gid of the named file.
-func (fs *Fs) Chown(path string, uid int, gid int) error {
-	return fs.mount.Chown(path, uint32(uid), uint32(gid))
+func (fs *Fs) Chown(path string, uid int, gid int) (err error) {
+	mount := fs.getMount()
+	defer fs.detectEviction(mount, &err)
+
+	if path, _, err = normPath("chown", path); err != nil {
+		return err
+	}
+	return mount.Chown(path, uint32(uid), uint32(gid))
 }
 
 // Chtimes changes the access and modification times of the named file
 func (fs *Fs) Chtimes(path string, atime time.Time, mtime time.Time) error {
-	return errors.New("not implemented")
+	return &os.PathError{Op: "chtimes", Path: path, Err: ErrTimesNotSupported}
 }
 
 // file implementation
 
+// File is an open file or directory. Its methods behave like those of
+// os.File: errors other than io.EOF are *os.PathError values wrapping a
+// syscall.Errno where ceph reports one, reading a directory fails with
+// EISDIR and listing a regular file fails with ENOTDIR.
+//
+// Directories are opened with both a file descriptor, used for Stat, Sync
+// and metadata changes, and a directory stream, used for Readdir.
 type File struct {
+	fs    *Fs
 	mount *gocephfs.MountInfo
 	path  string
 	file  *gocephfs.File
 	dir   *gocephfs.Directory
+	// flag holds the flags the file was opened with.
+	flag   int
+	closed bool
+	// sorted holds the remaining entries of a sorted Readdir, see
+	// WithSortedReaddir.
+	sorted *sortedDir
 }
 
 func (f *File) Name() string {
@@ -302,7 +407,35 @@ func (f *File) Name() string {
 }
 
 func (f *File) Close() error {
+	if f.closed {
+		return f.wrapErr("close", os.ErrClosed)
+	}
+	f.closed = true
+	if f.fs != nil {
+		defer f.fs.putMount(f.mount)
+	}
+
+	if f.stale() {
+		// the session of the mount the handles were opened on is gone,
+		// closing them only frees them
+		if f.file != nil {
+			f.file.Close()
+		}
+		if f.dir != nil {
+			f.dir.Close()
+		}
+		if f.sorted != nil {
+			f.sorted.close()
+		}
+		return nil
+	}
+
 	var errs []error
+	if f.file != nil && f.fs.syncOnClose && f.writable() {
+		if err := f.file.Sync(); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	if f.file != nil {
 		if err := f.file.Close(); err != nil {
 			errs = append(errs, err)
@@ -313,85 +446,198 @@ func (f *File) Close() error {
 			errs = append(errs, err)
 		}
 	}
+	if f.sorted != nil {
+		if err := f.sorted.close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	if len(errs) > 1 {
-		return fmt.Errorf("failed to close file for reasons; %w; %w", errs[0], errs[1])
+		return fmt.Errorf("failed to close file for reasons; %w", errors.Join(errs...))
 	}
 	if len(errs) == 1 {
-		return errs[0]
+		return f.wrapErr("close", errs[0])
 	}
 	return nil
 }
 
-var (
-	ErrDirDoesntSupport = errors.New("type of Dir does not support this operation")
-	ErrFileNil          = errors.New("cephfs file is nil, is this a directory?")
-	ErrDirNil           = errors.New("cephfs dir is nil, is this a file?")
-)
+// wrapErr turns err into the *os.PathError os.File would return for op.
+// Errors carrying a ceph return code are converted to the matching
+// syscall.Errno so errors.Is works with both syscall and fs errors.
+func (f *File) wrapErr(op string, err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	var pe *os.PathError
+	if errors.As(err, &pe) {
+		return err
+	}
+	return pathErr(op, f.path, err)
+}
 
-func (f *File) Read(buf []byte) (int, error) {
+// checkFile returns the error an operation on the file handle fails with
+// before it reaches libcephfs.
+func (f *File) checkFile(op string) error {
+	if f.closed {
+		return f.wrapErr(op, os.ErrClosed)
+	}
+	if f.stale() {
+		return f.wrapErr(op, ErrStaleFile)
+	}
 	if f.file == nil {
-		return 0, ErrFileNil
+		return f.wrapErr(op, ErrFileNil)
 	}
-	return f.file.Read(buf)
+	return nil
 }
 
-func (f *File) ReadAt(buf []byte, offset int64) (int, error) {
-	if f.file == nil {
-		return 0, ErrFileNil
+// checkData is checkFile for operations on file content, which directories
+// do not have.
+func (f *File) checkData(op string, errIfDir syscall.Errno) error {
+	if err := f.checkFile(op); err != nil {
+		return err
+	}
+	if f.dir != nil {
+		return f.wrapErr(op, errIfDir)
 	}
-	return f.file.ReadAt(buf, offset)
+	return nil
+}
+
+func (f *File) detectEviction(errp *error) {
+	f.fs.detectEvictionHeld(f.mount, errp)
+}
+
+func (f *File) Read(buf []byte) (int, error) {
+	return f.traceIO(context.Background(), "read", func() (int, error) {
+		return f.read(buf)
+	})
+}
+
+// ReadAt reads until buf is full like os.File.ReadAt, so a short read
+// always comes with an error, io.EOF at the end of the file.
+func (f *File) ReadAt(buf []byte, offset int64) (int, error) {
+	return f.traceIO(context.Background(), "read", func() (int, error) {
+		return f.readAt(buf, offset)
+	})
 }
 
 func (f *File) Write(buf []byte) (int, error) {
-	if f.file == nil {
-		return 0, ErrFileNil
-	}
-	return f.file.Write(buf)
+	return f.traceIO(context.Background(), "write", func() (int, error) {
+		return f.write(buf)
+	})
 }
 
 func (f *File) WriteAt(buf []byte, off int64) (int, error) {
-	if f.file == nil {
-		return 0, ErrFileNil
+	return f.traceIO(context.Background(), "write", func() (int, error) {
+		return f.writeAt(buf, off)
+	})
+}
+
+func (f *File) read(buf []byte) (_ int, err error) {
+	if err := f.checkData("read", syscall.EISDIR); err != nil {
+		return 0, err
 	}
-	return f.file.WriteAt(buf, off)
+	defer f.detectEviction(&err)
+	n, err := f.file.Read(buf)
+	return n, f.wrapErr("read", err)
 }
 
-func (f *File) Seek(offset int64, whence int) (int64, error) {
-	if f.file == nil {
-		return 0, ErrFileNil
+func (f *File) readAt(buf []byte, offset int64) (n int, err error) {
+	if err := f.checkData("read", syscall.EISDIR); err != nil {
+		return 0, err
 	}
-	return f.file.Seek(offset, whence)
+	defer f.detectEviction(&err)
+	for len(buf) > 0 {
+		m, err := f.file.ReadAt(buf, offset)
+		if err != nil {
+			return n, f.wrapErr("read", err)
+		}
+		n += m
+		buf = buf[m:]
+		offset += int64(m)
+	}
+	return n, nil
 }
 
-func (f *File) Stat() (os.FileInfo, error) {
-	if f.file == nil {
-		return nil, ErrFileNil
+func (f *File) write(buf []byte) (_ int, err error) {
+	if err := f.checkData("write", syscall.EBADF); err != nil {
+		return 0, err
 	}
-	stat, err := f.file.Fstatx(gocephfs.StatxBasicStats, 0)
+	defer f.detectEviction(&err)
+	n, err := f.file.Write(buf)
 	if err != nil {
+		return n, f.wrapErr("write", err)
+	}
+	return n, f.wrapErr("write", f.syncWrite())
+}
+
+func (f *File) writeAt(buf []byte, off int64) (_ int, err error) {
+	if err := f.checkData("write", syscall.EBADF); err != nil {
+		return 0, err
+	}
+	defer f.detectEviction(&err)
+	n, err := f.file.WriteAt(buf, off)
+	if err != nil {
+		return n, f.wrapErr("write", err)
+	}
+	return n, f.wrapErr("write", f.syncWrite())
+}
+
+// Seek sets the offset for the next Read or Write. On a directory only
+// Seek(0, io.SeekStart) is supported, which rewinds the directory so the
+// next Readdir starts from the first entry again.
+func (f *File) Seek(offset int64, whence int) (_ int64, err error) {
+	if err := f.checkFile("seek"); err != nil {
+		return 0, err
+	}
+	defer f.detectEviction(&err)
+	if f.dir != nil {
+		if offset != 0 || whence != io.SeekStart {
+			return 0, f.wrapErr("seek", syscall.EINVAL)
+		}
+		f.dir.RewindDir()
+		if f.sorted != nil {
+			err := f.sorted.close()
+			f.sorted = nil
+			return 0, f.wrapErr("seek", err)
+		}
+		return 0, nil
+	}
+	if whence == SeekData || whence == SeekHole {
+		pos, err := f.seekSparse(offset, whence)
+		return pos, f.wrapErr("seek", err)
+	}
+	pos, err := f.file.Seek(offset, whence)
+	return pos, f.wrapErr("seek", err)
+}
+
+func (f *File) Stat() (_ os.FileInfo, err error) {
+	if err := f.checkFile("stat"); err != nil {
 		return nil, err
 	}
+	defer f.detectEviction(&err)
+	stat, err := f.file.Fstatx(gocephfs.StatxBasicStats, 0)
+	if err != nil {
+		return nil, f.wrapErr("stat", err)
+	}
 	return &FileInfo{stat: stat, path: f.path}, nil
 }
 
-func (f *File) Sync() error {
-	if f.file == nil {
-		return ErrFileNil
+func (f *File) Sync() (err error) {
+	if err := f.checkFile("sync"); err != nil {
+		return err
 	}
-	return f.file.Sync()
+	defer f.detectEviction(&err)
+	return f.wrapErr("sync", f.file.Sync())
 }
 
-func (f *File) Truncate(size int64) error {
-	if f.file == nil {
-		return ErrFileNil
+func (f *File) Truncate(size int64) (err error) {
+	if err := f.checkData("truncate", syscall.EINVAL); err != nil {
+		return err
 	}
-	return f.file.Truncate(size)
+	defer f.detectEviction(&err)
+	return f.wrapErr("truncate", f.file.Truncate(size))
 }
 
 func (f *File) WriteString(s string) (int, error) {
-	if f.file == nil {
-		return 0, ErrFileNil
-	}
 	return f.Write([]byte(s))
 }
 
@@ -404,26 +650,89 @@ If n > 0, Readdir returns at most n FileInfo structures. In this case, if Readdi
 If n <= 0, Readdir returns all the FileInfo from the directory in a single slice. In this case, if Readdir succeeds (reads all the way to the end of the directory), it returns the slice and a nil error. If it encounters an error before the end of the directory, Readdir returns the FileInfo read until that point and a non-nil error.
 
 note:
-cephfs does not have any restriction on reproducible ordering of directories. if we run into issues with this in the future we'll have to redo this function. That would likely involve having our own read itterator and instead of reading one file at a time, we read them all (-1) and sort them before culling the list to the requested ammount and returning
+cephfs does not have any restriction on reproducible ordering of directories. Fs created WithSortedReaddir read all names, sort them and page through the sorted list instead, see readSorted.
 */
 func (f *File) Readdir(count int) ([]os.FileInfo, error) {
+	return f.readdir(context.Background(), count)
+}
+
+func (f *File) readdir(ctx context.Context, count int) (_ []os.FileInfo, err error) {
+	ctx, span := f.fs.startSpan(ctx, "readdir", Attribute{AttrPath, f.path}, Attribute{AttrCount, int64(count)})
+	lazy := f.fs.lazyReaddir
+	entries, err := f.readEntries(ctx, count, !lazy)
+	list := make([]os.FileInfo, 0, len(entries))
+	for _, de := range entries {
+		fullPath := filepath.Join(f.path, de.name)
+		if de.stat == nil {
+			list = append(list, &FileInfo{path: fullPath, fs: f.fs, dtype: de.dtype})
+		} else {
+			list = append(list, &FileInfo{stat: de.stat, path: fullPath})
+		}
+	}
+	span.end(err, Attribute{AttrEntries, int64(len(list))})
+	return list, err
+}
+
+// Readdirnames lists items in directory only by name. Unlike Readdir it
+// does not stat the entries.
+func (f *File) Readdirnames(count int) ([]string, error) {
+	return f.readdirnames(context.Background(), count)
+}
+
+func (f *File) readdirnames(ctx context.Context, count int) ([]string, error) {
+	ctx, span := f.fs.startSpan(ctx, "readdir", Attribute{AttrPath, f.path}, Attribute{AttrCount, int64(count)})
+	entries, err := f.readEntries(ctx, count, false)
+	list := make([]string, 0, len(entries))
+	for _, de := range entries {
+		list = append(list, de.name)
+	}
+	span.end(err, Attribute{AttrEntries, int64(len(list))})
+	return list, err
+}
+
+// dirent is an entry read from a directory. stat is nil if the entry was
+// read without stat, dtype is DTypeUnknown if it is not known.
+type dirent struct {
+	name  string
+	dtype gocephfs.DType
+	stat  *gocephfs.CephStatx
+}
+
+// readEntries reads up to count entries following the contract of
+// os.File.Readdir. Entries are stat'ed with the mask the Fs was configured
+// with if withStat is set.
+func (f *File) readEntries(ctx context.Context, count int, withStat bool) (_ []dirent, err error) {
+	if f.closed {
+		return nil, f.wrapErr("readdirent", os.ErrClosed)
+	}
+	if f.stale() {
+		return nil, f.wrapErr("readdirent", ErrStaleFile)
+	}
 	if f.dir == nil {
-		return nil, ErrDirNil
+		return nil, f.wrapErr("readdirent", syscall.ENOTDIR)
+	}
+	defer f.detectEviction(&err)
+
+	if f.fs.sortMemEntries > 0 {
+		return f.readSorted(ctx, count, withStat)
 	}
 
 	if count == 0 {
 		count = -1
 	}
 
-	list := make([]os.FileInfo, 0)
+	list := make([]dirent, 0)
 
 	for {
 		if count == 0 {
 			return list, nil
 		}
-		de, err := f.dir.ReadDirPlus(gocephfs.StatxBasicStats, 0)
+		if err := ctx.Err(); err != nil {
+			return list, err
+		}
+		de, err := f.readEntry(withStat)
 		if err != nil {
-			return list, fmt.Errorf("cephfs: failed to list file: %w", err)
+			return list, f.wrapErr("readdirent", err)
 		}
 		// de is nil at end of list
 		if de == nil {
@@ -435,11 +744,8 @@ func (f *File) Readdir(count int) ([]os.FileInfo, error) {
 		}
 
 		// dont list the current dir and parent dir
-		if name := de.Name(); name != "." && name != ".." {
-			fullPath := f.path + "/" + de.Name()
-			item := &FileInfo{stat: de.Statx(), path: fullPath}
-
-			list = append(list, item)
+		if name := de.name; name != "." && name != ".." {
+			list = append(list, *de)
 
 			if count > 0 {
 				count--
@@ -448,20 +754,56 @@ func (f *File) Readdir(count int) ([]os.FileInfo, error) {
 	}
 }
 
-// list items in directory only by name
-func (f *File) Readdirnames(count int) ([]string, error) {
-	deList, err := f.Readdir(count)
-	list := make([]string, 0)
-	for _, item := range deList {
-		list = append(list, item.Name())
+// readEntry reads the next entry of the directory stream, nil at its end.
+func (f *File) readEntry(withStat bool) (*dirent, error) {
+	if !withStat {
+		de, err := f.dir.ReadDir()
+		if de == nil || err != nil {
+			return nil, err
+		}
+		return &dirent{name: de.Name(), dtype: de.DType()}, nil
 	}
-	return list, err
+	de, err := f.dir.ReadDirPlus(f.fs.readdirMask, 0)
+	if de == nil || err != nil {
+		return nil, err
+	}
+	return &dirent{name: de.Name(), dtype: de.DType(), stat: de.Statx()}, nil
 }
 
 // implements os.FileInfo interface for CephFS.
+//
+// A FileInfo listed by an Fs created WithLazyReaddir has no stat yet. Its
+// Name and, where the directory entry tells, IsDir are answered without
+// one, the other methods stat the file on first use. If that fails, for
+// example because the file was removed, they report zero values. The stat
+// goes through the current mount of the Fs, so a FileInfo stays usable
+// after a remount.
 type FileInfo struct {
 	stat *gocephfs.CephStatx
 	path string
+
+	// fs and dtype are set for a FileInfo without stat, and never change
+	fs    *Fs
+	dtype gocephfs.DType
+	once  sync.Once
+}
+
+// load stats a lazily listed file on first use.
+func (info *FileInfo) load() *gocephfs.CephStatx {
+	info.once.Do(func() {
+		if info.stat != nil {
+			return
+		}
+		mount := info.fs.getMount()
+		stat, err := mount.Statx(info.path, gocephfs.StatxBasicStats, gocephfs.AtSymlinkNofollow)
+		info.fs.detectEviction(mount, &err)
+		if err != nil {
+			// the S_IFMT bits of a mode are the DT_ type shifted
+			stat = &gocephfs.CephStatx{Mode: uint16(info.dtype) << 12}
+		}
+		info.stat = stat
+	})
+	return info.stat
 }
 
 func (info *FileInfo) Name() string {
@@ -469,23 +811,31 @@ func (info *FileInfo) Name() string {
 }
 
 func (info *FileInfo) Size() int64 {
-	return int64(info.stat.Size)
+	return int64(info.load().Size)
 }
 
 func (info *FileInfo) Mode() os.FileMode {
-	return toFileMode(info.stat.Mode)
+	return toFileMode(info.load().Mode)
 }
 
 func (info *FileInfo) ModTime() time.Time {
-	return time.Unix(int64(info.stat.Mtime.Sec), int64(info.stat.Mtime.Nsec))
+	return timespecToTime(info.load().Mtime)
 }
 
 func (info *FileInfo) IsDir() bool {
+	// only the fields set up front are read, stat is written by load
+	if info.fs != nil && info.dtype != gocephfs.DTypeUnknown {
+		return info.dtype == gocephfs.DTypeDir
+	}
 	return info.Mode().IsDir()
 }
 
 func (info *FileInfo) Sys() interface{} {
-	return info.stat
+	return info.load()
+}
+
+func timespecToTime(ts gocephfs.Timespec) time.Time {
+	return time.Unix(int64(ts.Sec), int64(ts.Nsec))
 }
 
 func toFileMode(mode uint16) os.FileMode {
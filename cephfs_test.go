@@ -1,3 +1,5 @@
+//go:build cgo && !nocephfs
+
 // Copyright © 2014 Steve Francia <spf@spf13.com>.
 // Copyright 2009 The Go Authors. All rights reserved.
 //
@@ -15,7 +17,6 @@
 package cephfs_test
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -24,7 +25,6 @@ import (
 	"runtime"
 	"sort"
 	"strings"
-	"syscall"
 	"testing"
 
 	cephfs "github.com/crimsonfez/afero-cephfs"
@@ -169,56 +169,6 @@ func TestOpenFile(t *testing.T) {
 	}
 }
 
-func TestCreate(t *testing.T) {
-	defer removeAllTestFiles(t)
-	for _, fs := range Fss {
-		tmp := testDir(fs)
-		path := filepath.Join(tmp, testName)
-
-		f, err := fs.Create(path)
-		if err != nil {
-			t.Error(fs.Name(), "Create failed:", err)
-			f.Close()
-			continue
-		}
-		io.WriteString(f, "initial")
-		f.Close()
-
-		f, err = fs.Create(path)
-		if err != nil {
-			t.Error(fs.Name(), "Create failed:", err)
-			f.Close()
-			continue
-		}
-		secondContent := "second create"
-		io.WriteString(f, secondContent)
-		f.Close()
-
-		f, err = fs.Open(path)
-		if err != nil {
-			t.Error(fs.Name(), "Open failed:", err)
-			f.Close()
-			continue
-		}
-		buf, err := afero.ReadAll(f)
-		if err != nil {
-			t.Error(fs.Name(), "ReadAll failed:", err)
-			f.Close()
-			continue
-		}
-		if string(buf) != secondContent {
-			t.Error(
-				fs.Name(),
-				"Content should be",
-				"\""+secondContent+"\" but is \""+string(buf)+"\"",
-			)
-			f.Close()
-			continue
-		}
-		f.Close()
-	}
-}
-
 // readDirNames reads the directory named by dirname and returns
 // a sorted list of directory entries.
 // adapted from https://golang.org/src/path/filepath/path.go
@@ -236,231 +186,6 @@ func readDirNames(fs afero.Fs, dirname string) ([]string, error) {
 	return names, nil
 }
 
-func TestRename(t *testing.T) {
-	defer removeAllTestFiles(t)
-	for _, fs := range Fss {
-		tDir := testDir(fs)
-		from := filepath.Join(tDir, "/renamefrom")
-		to := filepath.Join(tDir, "/renameto")
-		exists := filepath.Join(tDir, "/renameexists")
-		file, err := fs.Create(from)
-		if err != nil {
-			t.Fatalf("%s: open %q failed: %v", fs.Name(), to, err)
-		}
-		if err = file.Close(); err != nil {
-			t.Errorf("%s: close %q failed: %v", fs.Name(), to, err)
-		}
-		file, err = fs.Create(exists)
-		if err != nil {
-			t.Fatalf("%s: open %q failed: %v", fs.Name(), to, err)
-		}
-		if err = file.Close(); err != nil {
-			t.Errorf("%s: close %q failed: %v", fs.Name(), to, err)
-		}
-		err = fs.Rename(from, to)
-		if err != nil {
-			t.Fatalf("%s: rename %q, %q failed: %v", fs.Name(), to, from, err)
-		}
-		file, err = fs.Create(from)
-		if err != nil {
-			t.Fatalf("%s: open %q failed: %v", fs.Name(), to, err)
-		}
-		if err = file.Close(); err != nil {
-			t.Errorf("%s: close %q failed: %v", fs.Name(), to, err)
-		}
-		err = fs.Rename(from, exists)
-		if err != nil {
-			t.Errorf("%s: rename %q, %q failed: %v", fs.Name(), exists, from, err)
-		}
-		names, err := readDirNames(fs, tDir)
-		if err != nil {
-			t.Errorf("%s: readDirNames error: %v", fs.Name(), err)
-		}
-		found := false
-		for _, e := range names {
-			if e == "renamefrom" {
-				t.Error("File is still called renamefrom")
-			}
-			if e == "renameto" {
-				found = true
-			}
-		}
-		if !found {
-			t.Error("File was not renamed to renameto")
-		}
-
-		_, err = fs.Stat(to)
-		if err != nil {
-			t.Errorf("%s: stat %q failed: %v", fs.Name(), to, err)
-		}
-	}
-}
-
-func TestRemove(t *testing.T) {
-	defer removeAllTestFiles(t)
-	for _, fs := range Fss {
-
-		x := tmpFile(fs)
-		defer x.Close()
-
-		path := x.Name()
-		x.Close()
-
-		tDir := filepath.Dir(path)
-
-		err := fs.Remove(path)
-		if err != nil {
-			t.Errorf("%v: Remove() failed: %v", fs.Name(), err)
-			continue
-		}
-
-		_, err = fs.Stat(path)
-		if !os.IsNotExist(err) {
-			t.Errorf("%v: Remove() didn't remove file", fs.Name())
-			continue
-		}
-
-		// Deleting non-existent file should raise error
-		err = fs.Remove(path)
-		if !os.IsNotExist(err) {
-			t.Errorf("%v: Remove() didn't raise error for non-existent file", fs.Name())
-		}
-
-		f, err := fs.Open(tDir)
-		if err != nil {
-			t.Error("TestDir should still exist:", err)
-		}
-
-		names, err := f.Readdirnames(-1)
-		if err != nil {
-			t.Error("Readdirnames failed:", err)
-		}
-
-		for _, e := range names {
-			if e == testName {
-				t.Error("File was not removed from parent directory")
-			}
-		}
-	}
-}
-
-func TestTruncate(t *testing.T) {
-	defer removeAllTestFiles(t)
-	for _, fs := range Fss {
-		f := tmpFile(fs)
-		defer f.Close()
-
-		checkSize(t, f, 0)
-		f.Write([]byte("hello, world\n"))
-		checkSize(t, f, 13)
-		f.Truncate(10)
-		checkSize(t, f, 10)
-		f.Truncate(1024)
-		checkSize(t, f, 1024)
-		f.Truncate(0)
-		checkSize(t, f, 0)
-		_, err := f.Write([]byte("surprise!"))
-		if err == nil {
-			checkSize(t, f, 13+9) // wrote at offset past where hello, world was.
-		}
-	}
-}
-
-func TestSeek(t *testing.T) {
-	defer removeAllTestFiles(t)
-	for _, fs := range Fss {
-		f := tmpFile(fs)
-		defer f.Close()
-
-		const data = "hello, world\n"
-		io.WriteString(f, data)
-
-		type test struct {
-			in     int64
-			whence int
-			out    int64
-		}
-		tests := []test{
-			{0, 1, int64(len(data))},
-			{0, 0, 0},
-			{5, 0, 5},
-			{0, 2, int64(len(data))},
-			{0, 0, 0},
-			{-1, 2, int64(len(data)) - 1},
-			{1 << 33, 0, 1 << 33},
-			{1 << 33, 2, 1<<33 + int64(len(data))},
-		}
-		for i, tt := range tests {
-			off, err := f.Seek(tt.in, tt.whence)
-			if off != tt.out || err != nil {
-				if e, ok := err.(*os.PathError); ok && e.Err == syscall.EINVAL && tt.out > 1<<32 {
-					// Reiserfs rejects the big seeks.
-					// http://code.google.com/p/go/issues/detail?id=91
-					break
-				}
-				t.Errorf(
-					"#%d: Seek(%v, %v) = %v, %v want %v, nil",
-					i,
-					tt.in,
-					tt.whence,
-					off,
-					err,
-					tt.out,
-				)
-			}
-		}
-	}
-}
-
-func TestReadAt(t *testing.T) {
-	defer removeAllTestFiles(t)
-	for _, fs := range Fss {
-		f := tmpFile(fs)
-		defer f.Close()
-
-		const data = "hello, world\n"
-		io.WriteString(f, data)
-
-		b := make([]byte, 5)
-		n, err := f.ReadAt(b, 7)
-		if err != nil || n != len(b) {
-			t.Fatalf("ReadAt 7: %d, %v", n, err)
-		}
-		if string(b) != "world" {
-			t.Fatalf("ReadAt 7: have %q want %q", string(b), "world")
-		}
-	}
-}
-
-func TestWriteAt(t *testing.T) {
-	defer removeAllTestFiles(t)
-	for _, fs := range Fss {
-		f := tmpFile(fs)
-		defer f.Close()
-
-		const data = "hello, world\n"
-		io.WriteString(f, data)
-
-		n, err := f.WriteAt([]byte("WORLD"), 7)
-		if err != nil || n != 5 {
-			t.Fatalf("WriteAt 7: %d, %v", n, err)
-		}
-
-		f2, err := fs.Open(f.Name())
-		if err != nil {
-			t.Fatalf("%v: ReadFile %s: %v", fs.Name(), f.Name(), err)
-		}
-		defer f2.Close()
-		buf := new(bytes.Buffer)
-		buf.ReadFrom(f2)
-		b := buf.Bytes()
-		if string(b) != "hello, WORLD\n" {
-			t.Fatalf("after write: have %q want %q", string(b), "hello, WORLD\n")
-		}
-
-	}
-}
-
 func setupTestDir(t *testing.T, fs afero.Fs) string {
 	path := testDir(fs)
 	return setupTestFiles(t, fs, path)
@@ -522,52 +247,6 @@ func TestReaddirRegularFile(t *testing.T) {
 	}
 }
 
-func setupTestDirStructure(fs afero.Fs) string {
-	dirPath := testDir(fs)
-	for _, path := range []string{"file1", "file2", "file3"} {
-		_, err := fs.Create(dirPath + "/" + path)
-		if err != nil {
-			panic(err)
-		}
-	}
-	return dirPath
-}
-
-func TestReaddirCount(t *testing.T) {
-	for _, fs := range Fss {
-		dirPath := setupTestDirStructure(fs)
-		defer removeAllTestFiles(t)
-
-		root, err := fs.Open(dirPath)
-		assert.NoError(t, err)
-
-		dirs1, err := root.Readdirnames(2)
-		assert.NoError(t, err)
-		assert.Equal(t, int(2), len(dirs1))
-
-		// verify that we increment and list the next file
-		dirs2, err := root.Readdirnames(1)
-		assert.NoError(t, err)
-		assert.Equal(t, int(1), len(dirs2))
-		assert.NotContains(t, dirs1, dirs2[0])
-
-		// err when reaching end of dir
-		_, err = root.Readdirnames(1)
-		assert.ErrorIs(t, err, io.EOF)
-
-		// re-open to rewind
-		root.Close()
-		root, err = fs.Open(dirPath)
-		assert.NoError(t, err)
-
-		// when reading more than the length of dir, return list and io.EOF
-		dirs, err := root.Readdirnames(4)
-		assert.ErrorIs(t, err, io.EOF)
-		assert.Equal(t, int(3), len(dirs))
-		root.Close()
-	}
-}
-
 func TestReaddir(t *testing.T) {
 	for _, fs := range Fss {
 		dirPath := testDir(fs)
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/abiosoft/ishell"
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"github.com/spf13/afero"
+)
+
+// removeTree removes path and everything in it, reporting progress while
+// removing large trees from cephfs.
+func removeTree(c *ishell.Context, fs afero.Fs, path string) {
+	mount, ok := fs.(*cephfs.Fs)
+	if !ok {
+		if err := fs.RemoveAll(path); err != nil {
+			c.Err(fmt.Errorf("failed to remove tree: %w", err))
+		}
+		return
+	}
+
+	var removed int64
+	err := mount.RemoveAllWithOptions(path, &cephfs.RemoveAllOptions{
+		Parallelism: 8,
+		Progress: func(path string, n int64) {
+			removed = n
+			if n%1000 == 0 {
+				c.Printf("\rremoved %d entries", n)
+			}
+		},
+	})
+	c.Printf("\rremoved %d entries\n", removed)
+	if err != nil {
+		c.Err(fmt.Errorf("failed to remove tree: %w", err))
+	}
+}
+
+// addCephCmds adds the commands that need the cephfs backend.
+func addCephCmds(shell *ishell.Shell, fs afero.Fs) {
+	shell.AddCmd(&ishell.Cmd{
+		Name: "trash",
+		Func: func(c *ishell.Context) {
+			mount, ok := fs.(*cephfs.Fs)
+			if !ok {
+				c.Err(fmt.Errorf("trash is only supported by the cephfs backend"))
+				return
+			}
+			trash := cephfs.NewTrashFs(mount, "")
+
+			switch {
+			case len(c.Args) == 0:
+				entries, err := trash.ListTrash()
+				if err != nil {
+					c.Err(fmt.Errorf("failed to list trash: %v", err))
+					return
+				}
+				for _, entry := range entries {
+					c.Printf("%s\t%s\t%s\n", entry.DeletedAt.Local().Format(time.DateTime), entry.OriginalPath, entry.Path)
+				}
+			case c.Args[0] == "-purge":
+				if len(c.Args) != 2 {
+					c.Err(fmt.Errorf("usage: trash -purge <age>"))
+					return
+				}
+				age, err := time.ParseDuration(c.Args[1])
+				if err != nil {
+					c.Err(fmt.Errorf("failed to parse age: %w", err))
+					return
+				}
+				purged, err := trash.PurgeOlderThan(age)
+				for _, path := range purged {
+					c.Println("purged", path)
+				}
+				if err != nil {
+					c.Err(fmt.Errorf("failed to purge trash: %v", err))
+				}
+			case len(c.Args) > 1:
+				c.Err(fmt.Errorf("multiple inputs provided, we only expect one"))
+			default:
+				if err := trash.RemoveAll(c.Args[0]); err != nil {
+					c.Err(fmt.Errorf("failed to move to trash: %v", err))
+				}
+			}
+		},
+	})
+
+	shell.AddCmd(&ishell.Cmd{
+		Name: "restore",
+		Func: func(c *ishell.Context) {
+			if len(c.Args) == 0 {
+				c.Err(fmt.Errorf("you must provide a path in the trash"))
+				return
+			}
+			if len(c.Args) > 1 {
+				c.Err(fmt.Errorf("multiple inputs provided, we only expect one"))
+				return
+			}
+
+			mount, ok := fs.(*cephfs.Fs)
+			if !ok {
+				c.Err(fmt.Errorf("restore is only supported by the cephfs backend"))
+				return
+			}
+
+			if err := cephfs.NewTrashFs(mount, "").Restore(c.Args[0]); err != nil {
+				c.Err(fmt.Errorf("failed to restore: %v", err))
+			}
+		},
+	})
+
+	shell.AddCmd(&ishell.Cmd{
+		Name: "df",
+		Func: func(c *ishell.Context) {
+			if len(c.Args) > 1 {
+				c.Err(fmt.Errorf("multiple inputs provided, we only expect one"))
+				return
+			}
+
+			path := "/"
+			if len(c.Args) == 1 {
+				path = c.Args[0]
+			}
+
+			mount, ok := fs.(*cephfs.Fs)
+			if !ok {
+				c.Err(fmt.Errorf("df is only supported by the cephfs backend"))
+				return
+			}
+
+			usage, err := mount.Statfs(path)
+			if err != nil {
+				c.Err(fmt.Errorf("failed to get disk usage: %v", err))
+				return
+			}
+			jusage, err := json.MarshalIndent(usage, "", "  ")
+			if err != nil {
+				c.Err(fmt.Errorf("failed to marshal disk usage: %v", err))
+				return
+			}
+			c.Println(string(jusage))
+		},
+	})
+}
@@ -177,22 +177,34 @@ func main() {
 	shell.AddCmd(&ishell.Cmd{
 		Name: "rm",
 		Func: func(c *ishell.Context) {
-			if len(c.Args) == 0 {
+			args := c.Args
+			recursive := len(args) > 0 && args[0] == "-r"
+			if recursive {
+				args = args[1:]
+			}
+			if len(args) == 0 {
 				c.Err(fmt.Errorf("you must provide a file path"))
 				return
 			}
-			if len(c.Args) > 1 {
+			if len(args) > 1 {
 				c.Err(fmt.Errorf("multiple inputs provided, we only expect one"))
 				return
 			}
 
-			path := c.Args[0]
+			path := args[0]
 
-			if err := fs.Remove(path); err != nil {
-				c.Err(fmt.Errorf("failed to remove file: %w", err))
+			if !recursive {
+				if err := fs.Remove(path); err != nil {
+					c.Err(fmt.Errorf("failed to remove file: %w", err))
+				}
+				return
 			}
+
+			removeTree(c, fs, path)
 		},
 	})
 
+	addCephCmds(shell, fs)
+
 	shell.Run()
 }
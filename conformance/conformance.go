@@ -0,0 +1,484 @@
+// Package conformance is a test suite for afero.Fs implementations. It
+// checks the behaviour callers of os rely on, so running it against
+// several backends, and the wrappers around them, shows where they agree
+// and where they do not.
+package conformance
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+// Run runs the suite as subtests of t. newFs is called for every subtest
+// and may return the same Fs each time. Every subtest works in a new
+// directory below dir, which must exist in the Fs, and removes it when
+// done. Subtests named in skip are reported as skipped, for backends with
+// known deviations.
+func Run(t *testing.T, newFs func() afero.Fs, dir string, skip ...string) {
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if slices.Contains(skip, test.name) {
+				t.Skip("known deviation")
+			}
+			fs := newFs()
+			test.run(t, fs, tempDir(t, fs, dir))
+		})
+	}
+}
+
+var tests = []struct {
+	name string
+	run  func(t *testing.T, fs afero.Fs, dir string)
+}{
+	{"Create", testCreate},
+	{"Rename", testRename},
+	{"Remove", testRemove},
+	{"RemoveAll", testRemoveAll},
+	{"Truncate", testTruncate},
+	{"Seek", testSeek},
+	{"ReadAtWriteAt", testReadAtWriteAt},
+	{"ReaddirPaging", testReaddirPaging},
+	{"Errors", testErrors},
+	{"Permissions", testPermissions},
+}
+
+func tempDir(t *testing.T, fs afero.Fs, base string) string {
+	t.Helper()
+	dir, err := afero.TempDir(fs, base, "afero-conformance")
+	if err != nil {
+		t.Fatalf("failed to create test directory: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := fs.RemoveAll(dir); err != nil {
+			t.Errorf("failed to remove test directory: %v", err)
+		}
+	})
+	return dir
+}
+
+func writeFile(t *testing.T, fs afero.Fs, path, content string) {
+	t.Helper()
+	if err := afero.WriteFile(fs, path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func checkContent(t *testing.T, fs afero.Fs, path, want string) {
+	t.Helper()
+	got, err := afero.ReadFile(fs, path)
+	if err != nil {
+		t.Errorf("failed to read %s: %v", path, err)
+		return
+	}
+	if string(got) != want {
+		t.Errorf("content of %s = %q, want %q", path, got, want)
+	}
+}
+
+func checkNotExist(t *testing.T, fs afero.Fs, path string) {
+	t.Helper()
+	if _, err := fs.Stat(path); !errors.Is(err, iofs.ErrNotExist) {
+		t.Errorf("Stat(%s) = %v, want %v", path, err, iofs.ErrNotExist)
+	}
+}
+
+func testCreate(t *testing.T, fs afero.Fs, dir string) {
+	path := filepath.Join(dir, "file")
+
+	f, err := fs.Create(path)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := io.WriteString(f, "initial content"); err != nil {
+		t.Errorf("write to created file failed: %v", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Errorf("seek in created file failed: %v", err)
+	}
+	if got, err := io.ReadAll(f); err != nil || string(got) != "initial content" {
+		t.Errorf("read from created file = %q, %v", got, err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+
+	f, err = fs.Create(path)
+	if err != nil {
+		t.Fatalf("Create of existing file failed: %v", err)
+	}
+	io.WriteString(f, "second")
+	f.Close()
+	checkContent(t, fs, path, "second")
+}
+
+func testRename(t *testing.T, fs afero.Fs, dir string) {
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	writeFile(t, fs, src, "src")
+
+	if err := fs.Rename(src, dst); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	checkNotExist(t, fs, src)
+	checkContent(t, fs, dst, "src")
+
+	writeFile(t, fs, src, "replacement")
+	if err := fs.Rename(src, dst); err != nil {
+		t.Fatalf("Rename onto existing file failed: %v", err)
+	}
+	checkContent(t, fs, dst, "replacement")
+
+	if err := fs.Rename(src, dst); !errors.Is(err, iofs.ErrNotExist) {
+		t.Errorf("Rename of missing file = %v, want %v", err, iofs.ErrNotExist)
+	}
+
+	subdir := filepath.Join(dir, "subdir")
+	renamed := filepath.Join(dir, "renamed")
+	if err := fs.Mkdir(subdir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fs, filepath.Join(subdir, "file"), "nested")
+	if err := fs.Rename(subdir, renamed); err != nil {
+		t.Fatalf("Rename of directory failed: %v", err)
+	}
+	checkContent(t, fs, filepath.Join(renamed, "file"), "nested")
+}
+
+func testRemove(t *testing.T, fs afero.Fs, dir string) {
+	path := filepath.Join(dir, "file")
+	subdir := filepath.Join(dir, "subdir")
+	writeFile(t, fs, path, "content")
+	if err := fs.Mkdir(subdir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fs, filepath.Join(subdir, "file"), "content")
+
+	if err := fs.Remove(path); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	checkNotExist(t, fs, path)
+
+	if err := fs.Remove(path); !errors.Is(err, iofs.ErrNotExist) {
+		t.Errorf("Remove of missing file = %v, want %v", err, iofs.ErrNotExist)
+	}
+	if err := fs.Remove(subdir); err == nil {
+		t.Errorf("Remove of non-empty directory succeeded")
+	}
+	if err := fs.Remove(filepath.Join(subdir, "file")); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Remove(subdir); err != nil {
+		t.Errorf("Remove of empty directory failed: %v", err)
+	}
+	checkNotExist(t, fs, subdir)
+}
+
+func testRemoveAll(t *testing.T, fs afero.Fs, dir string) {
+	root := filepath.Join(dir, "tree")
+	if err := fs.MkdirAll(filepath.Join(root, "a", "b", "c"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, fs, filepath.Join(root, "file"), "content")
+	writeFile(t, fs, filepath.Join(root, "a", "b", "file"), "content")
+
+	if err := fs.RemoveAll(root); err != nil {
+		t.Fatalf("RemoveAll failed: %v", err)
+	}
+	checkNotExist(t, fs, root)
+
+	if err := fs.RemoveAll(root); err != nil {
+		t.Errorf("RemoveAll of missing path = %v, want nil", err)
+	}
+
+	path := filepath.Join(dir, "file")
+	writeFile(t, fs, path, "content")
+	if err := fs.RemoveAll(path); err != nil {
+		t.Errorf("RemoveAll of file failed: %v", err)
+	}
+	checkNotExist(t, fs, path)
+}
+
+func testTruncate(t *testing.T, fs afero.Fs, dir string) {
+	path := filepath.Join(dir, "file")
+	f, err := fs.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	io.WriteString(f, "0123456789")
+
+	for _, size := range []int64{5, 12, 0} {
+		if err := f.Truncate(size); err != nil {
+			t.Fatalf("Truncate(%d) failed: %v", size, err)
+		}
+		info, err := f.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() != size {
+			t.Errorf("size after Truncate(%d) = %d", size, info.Size())
+		}
+		if size == 12 {
+			buf := make([]byte, 12)
+			if _, err := f.ReadAt(buf, 0); err != nil && err != io.EOF {
+				t.Fatal(err)
+			}
+			if want := "01234\x00\x00\x00\x00\x00\x00\x00"; string(buf) != want {
+				t.Errorf("content after growing = %q, want %q", buf, want)
+			}
+		}
+	}
+}
+
+func testSeek(t *testing.T, fs afero.Fs, dir string) {
+	path := filepath.Join(dir, "file")
+	f, err := fs.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	io.WriteString(f, "0123456789")
+
+	for _, tc := range []struct {
+		offset int64
+		whence int
+		pos    int64
+	}{
+		{0, io.SeekStart, 0},
+		{3, io.SeekStart, 3},
+		{2, io.SeekCurrent, 5},
+		{-1, io.SeekCurrent, 4},
+		{0, io.SeekEnd, 10},
+		{-3, io.SeekEnd, 7},
+		{5, io.SeekEnd, 15},
+	} {
+		pos, err := f.Seek(tc.offset, tc.whence)
+		if err != nil || pos != tc.pos {
+			t.Errorf("Seek(%d, %d) = %d, %v, want %d", tc.offset, tc.whence, pos, err, tc.pos)
+		}
+	}
+
+	if _, err := f.Seek(7, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(f, buf); err != nil || string(buf) != "78" {
+		t.Errorf("read after seek = %q, %v, want %q", buf, err, "78")
+	}
+
+	if _, err := f.Seek(-1, io.SeekStart); err == nil {
+		t.Errorf("Seek to negative offset succeeded")
+	}
+}
+
+func testReadAtWriteAt(t *testing.T, fs afero.Fs, dir string) {
+	path := filepath.Join(dir, "file")
+	f, err := fs.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteAt([]byte("abc"), 0); err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+	if _, err := f.WriteAt([]byte("xyz"), 6); err != nil {
+		t.Fatalf("WriteAt past end failed: %v", err)
+	}
+	if _, err := f.WriteAt([]byte("B"), 1); err != nil {
+		t.Fatalf("WriteAt inside file failed: %v", err)
+	}
+
+	buf := make([]byte, 9)
+	n, err := f.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		t.Fatalf("ReadAt failed: %v", err)
+	}
+	if want := "aBc\x00\x00\x00xyz"; string(buf[:n]) != want {
+		t.Errorf("content = %q, want %q", buf[:n], want)
+	}
+
+	n, err = f.ReadAt(buf, 7)
+	if n != 2 || err != io.EOF || string(buf[:n]) != "yz" {
+		t.Errorf("ReadAt across end = %d %q, %v, want 2 %q, %v", n, buf[:n], err, "yz", io.EOF)
+	}
+
+	n, err = f.ReadAt(buf[:0], 3)
+	if n != 0 || err != nil {
+		t.Errorf("ReadAt of zero bytes = %d, %v, want 0, nil", n, err)
+	}
+}
+
+// testReaddirPaging checks the paging contract of Readdir and Readdirnames.
+// A page of fewer than n entries may come with io.EOF or the next, empty,
+// page does; os does the latter.
+func testReaddirPaging(t *testing.T, fs afero.Fs, dir string) {
+	var want []string
+	for i := 0; i < 7; i++ {
+		name := fmt.Sprintf("file%d", i)
+		writeFile(t, fs, filepath.Join(dir, name), "")
+		want = append(want, name)
+	}
+
+	for _, names := range []bool{false, true} {
+		f, err := fs.Open(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for calls := 0; ; calls++ {
+			if calls > len(want) {
+				t.Fatalf("Readdir did not reach io.EOF")
+			}
+			var page []string
+			if names {
+				page, err = f.Readdirnames(3)
+			} else {
+				var infos []os.FileInfo
+				infos, err = f.Readdir(3)
+				for _, info := range infos {
+					page = append(page, info.Name())
+				}
+			}
+			if len(page) > 3 {
+				t.Errorf("page of %d entries, want at most 3", len(page))
+			}
+			if len(page) == 0 && err == nil {
+				t.Fatalf("empty page without error")
+			}
+			got = append(got, page...)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Readdir failed: %v", err)
+			}
+		}
+
+		if page, err := f.Readdirnames(3); len(page) != 0 || err != io.EOF {
+			t.Errorf("Readdirnames after end = %v, %v, want no entries and %v", page, err, io.EOF)
+		}
+		f.Close()
+
+		sort.Strings(got)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("paged listing = %v, want %v", got, want)
+		}
+	}
+
+	f, err := fs.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	all, err := f.Readdirnames(-1)
+	if err != nil || len(all) != len(want) {
+		t.Errorf("Readdirnames(-1) = %d names, %v, want %d names", len(all), err, len(want))
+	}
+	if rest, err := f.Readdirnames(-1); len(rest) != 0 || err != nil {
+		t.Errorf("Readdirnames(-1) after end = %v, %v, want no entries and nil", rest, err)
+	}
+}
+
+func testErrors(t *testing.T, fs afero.Fs, dir string) {
+	missing := filepath.Join(dir, "missing")
+	path := filepath.Join(dir, "file")
+	writeFile(t, fs, path, "content")
+
+	if _, err := fs.Open(missing); !errors.Is(err, iofs.ErrNotExist) {
+		t.Errorf("Open of missing file = %v, want %v", err, iofs.ErrNotExist)
+	}
+	if _, err := fs.Stat(missing); !errors.Is(err, iofs.ErrNotExist) {
+		t.Errorf("Stat of missing file = %v, want %v", err, iofs.ErrNotExist)
+	}
+	if _, err := fs.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644); !errors.Is(err, iofs.ErrExist) {
+		t.Errorf("OpenFile with O_EXCL of existing file = %v, want %v", err, iofs.ErrExist)
+	}
+	if err := fs.Mkdir(dir, 0o755); !errors.Is(err, iofs.ErrExist) {
+		t.Errorf("Mkdir of existing directory = %v, want %v", err, iofs.ErrExist)
+	}
+	if err := fs.Mkdir(filepath.Join(missing, "dir"), 0o755); err == nil {
+		t.Errorf("Mkdir in missing directory succeeded")
+	}
+
+	f, err := fs.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("data")); err == nil {
+		t.Errorf("Write to file opened read-only succeeded")
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Read(make([]byte, 4)); err == nil {
+		t.Errorf("Read from closed file succeeded")
+	}
+	checkContent(t, fs, path, "content")
+}
+
+func testPermissions(t *testing.T, fs afero.Fs, dir string) {
+	path := filepath.Join(dir, "file")
+	subdir := filepath.Join(dir, "subdir")
+
+	f, err := fs.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o640)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	checkPerm(t, fs, path, 0o640)
+
+	if err := fs.Chmod(path, 0o600); err != nil {
+		t.Fatalf("Chmod failed: %v", err)
+	}
+	checkPerm(t, fs, path, 0o600)
+
+	if err := fs.Mkdir(subdir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	info, err := fs.Stat(subdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.IsDir() || !info.Mode().IsDir() {
+		t.Errorf("mode of directory = %v, not a directory", info.Mode())
+	}
+	checkPerm(t, fs, subdir, 0o750)
+
+	if f, err := fs.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o777); err != nil {
+		t.Fatal(err)
+	} else {
+		f.Close()
+	}
+	checkPerm(t, fs, path, 0o600)
+
+	if err := afero.WriteFile(fs, path, []byte("content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := afero.ReadFile(fs, path)
+	if err != nil || !bytes.Equal(got, []byte("content")) {
+		t.Errorf("content after rewriting = %q, %v", got, err)
+	}
+}
+
+func checkPerm(t *testing.T, fs afero.Fs, path string, want os.FileMode) {
+	t.Helper()
+	info, err := fs.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != want {
+		t.Errorf("permissions of %s = %v, want %v", path, info.Mode().Perm(), want)
+	}
+}
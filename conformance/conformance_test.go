@@ -0,0 +1,25 @@
+package conformance_test
+
+import (
+	"testing"
+
+	"github.com/crimsonfez/afero-cephfs/conformance"
+	"github.com/spf13/afero"
+)
+
+func TestOsFs(t *testing.T) {
+	conformance.Run(t, afero.NewOsFs, t.TempDir())
+}
+
+// MemMapFs removes non-empty directories, leaving their children behind,
+// allows seeking before the start of a file, returns nil instead of io.EOF
+// from a short ReadAt and creates directories in missing parents.
+func TestMemMapFs(t *testing.T) {
+	conformance.Run(t, afero.NewMemMapFs, "/", "Remove", "Seek", "ReadAtWriteAt", "Errors")
+}
+
+func TestBasePathFs(t *testing.T) {
+	conformance.Run(t, func() afero.Fs {
+		return afero.NewBasePathFs(afero.NewOsFs(), t.TempDir())
+	}, "/")
+}
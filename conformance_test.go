@@ -0,0 +1,39 @@
+//go:build cgo && !nocephfs
+
+package cephfs_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"github.com/crimsonfez/afero-cephfs/conformance"
+	"github.com/spf13/afero"
+)
+
+func TestConformance(t *testing.T) {
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		defer removeAllTestFiles(t)
+		dir := "/" + testDir(fs)
+
+		t.Run("Fs", func(t *testing.T) {
+			conformance.Run(t, func() afero.Fs { return cfs }, dir)
+		})
+		t.Run("ContextFs", func(t *testing.T) {
+			conformance.Run(t, func() afero.Fs { return cfs.WithContext(context.Background()) }, dir)
+		})
+		t.Run("RetryFs", func(t *testing.T) {
+			conformance.Run(t, func() afero.Fs { return cephfs.NewRetryFs(cfs, cephfs.RetryPolicy{}) }, dir)
+		})
+		t.Run("TrashFs", func(t *testing.T) {
+			trash := cephfs.NewTrashFs(cfs, filepath.Join(dir, ".trash"))
+			conformance.Run(t, func() afero.Fs { return trash }, dir)
+		})
+	}
+}
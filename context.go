@@ -0,0 +1,213 @@
+//go:build cgo && !nocephfs
+
+package cephfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// contextIOChunk bounds how much data a single libcephfs read or write
+// issued through a ContextFile transfers before the context is checked
+// again.
+const contextIOChunk = 1 << 20
+
+// ContextFs is a view of an Fs whose operations observe the cancellation
+// and deadline of a context. It implements afero.Fs.
+//
+// A libcephfs call that is already in flight cannot be interrupted, so the
+// context is checked before every call. Long operations such as RemoveAll,
+// Readdir, Walk and large reads and writes are split into many calls and
+// stop with the context's error as soon as it is done. No goroutines are
+// started, so nothing is left running after a cancelled operation returns.
+type ContextFs struct {
+	fs  *Fs
+	ctx context.Context
+}
+
+// WithContext returns a view of fs bound to ctx.
+func (fs *Fs) WithContext(ctx context.Context) *ContextFs {
+	return &ContextFs{fs: fs, ctx: ctx}
+}
+
+// Context returns the context the view is bound to.
+func (cfs *ContextFs) Context() context.Context {
+	return cfs.ctx
+}
+
+func (cfs *ContextFs) Create(path string) (afero.File, error) {
+	if err := cfs.ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := cfs.fs.openFile(cfs.ctx, path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, err
+	}
+	return &ContextFile{f, cfs.ctx}, nil
+}
+
+func (cfs *ContextFs) Mkdir(path string, perm os.FileMode) error {
+	if err := cfs.ctx.Err(); err != nil {
+		return err
+	}
+	return cfs.fs.Mkdir(path, perm)
+}
+
+func (cfs *ContextFs) MkdirAll(path string, perm os.FileMode) error {
+	if err := cfs.ctx.Err(); err != nil {
+		return err
+	}
+	return cfs.fs.MkdirAll(path, perm)
+}
+
+func (cfs *ContextFs) Open(path string) (afero.File, error) {
+	return cfs.OpenFile(path, os.O_RDONLY, 0)
+}
+
+func (cfs *ContextFs) OpenFile(path string, flag int, perm os.FileMode) (afero.File, error) {
+	if err := cfs.ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := cfs.fs.openFile(cfs.ctx, path, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &ContextFile{f, cfs.ctx}, nil
+}
+
+func (cfs *ContextFs) Remove(path string) error {
+	if err := cfs.ctx.Err(); err != nil {
+		return err
+	}
+	return cfs.fs.Remove(path)
+}
+
+// RemoveAll behaves like Fs.RemoveAll, checking the context before every
+// entry it removes.
+func (cfs *ContextFs) RemoveAll(path string) error {
+	return cfs.fs.removeAll(cfs.ctx, path, nil)
+}
+
+func (cfs *ContextFs) Rename(oldPath, newPath string) error {
+	if err := cfs.ctx.Err(); err != nil {
+		return err
+	}
+	return cfs.fs.rename(cfs.ctx, oldPath, newPath)
+}
+
+func (cfs *ContextFs) Stat(path string) (os.FileInfo, error) {
+	if err := cfs.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return cfs.fs.stat(cfs.ctx, path)
+}
+
+func (cfs *ContextFs) Name() string {
+	return cfs.fs.Name()
+}
+
+func (cfs *ContextFs) Chmod(path string, mode os.FileMode) error {
+	if err := cfs.ctx.Err(); err != nil {
+		return err
+	}
+	return cfs.fs.Chmod(path, mode)
+}
+
+func (cfs *ContextFs) Chown(path string, uid int, gid int) error {
+	if err := cfs.ctx.Err(); err != nil {
+		return err
+	}
+	return cfs.fs.Chown(path, uid, gid)
+}
+
+func (cfs *ContextFs) Chtimes(path string, atime time.Time, mtime time.Time) error {
+	if err := cfs.ctx.Err(); err != nil {
+		return err
+	}
+	return cfs.fs.Chtimes(path, atime, mtime)
+}
+
+// Walk walks the file tree rooted at root like filepath.Walk, stopping with
+// the context's error once it is done.
+func (cfs *ContextFs) Walk(root string, walkFn filepath.WalkFunc) error {
+	return afero.Walk(cfs, root, func(path string, info os.FileInfo, err error) error {
+		if ctxErr := cfs.ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return walkFn(path, info, err)
+	})
+}
+
+// ContextFile is a File opened through a ContextFs. Reads, writes and
+// directory listings observe the context of the ContextFs.
+type ContextFile struct {
+	*File
+	ctx context.Context
+}
+
+func (f *ContextFile) Read(buf []byte) (int, error) {
+	return f.traceIO(f.ctx, "read", func() (int, error) {
+		return chunkedIO(f.ctx, buf, func(b []byte, _ int64) (int, error) {
+			return f.File.read(b)
+		})
+	})
+}
+
+func (f *ContextFile) ReadAt(buf []byte, offset int64) (int, error) {
+	return f.traceIO(f.ctx, "read", func() (int, error) {
+		return chunkedIO(f.ctx, buf, func(b []byte, done int64) (int, error) {
+			return f.File.readAt(b, offset+done)
+		})
+	})
+}
+
+func (f *ContextFile) Write(buf []byte) (int, error) {
+	return f.traceIO(f.ctx, "write", func() (int, error) {
+		return chunkedIO(f.ctx, buf, func(b []byte, _ int64) (int, error) {
+			return f.File.write(b)
+		})
+	})
+}
+
+func (f *ContextFile) WriteAt(buf []byte, offset int64) (int, error) {
+	return f.traceIO(f.ctx, "write", func() (int, error) {
+		return chunkedIO(f.ctx, buf, func(b []byte, done int64) (int, error) {
+			return f.File.writeAt(b, offset+done)
+		})
+	})
+}
+
+func (f *ContextFile) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+func (f *ContextFile) Readdir(count int) ([]os.FileInfo, error) {
+	return f.readdir(f.ctx, count)
+}
+
+func (f *ContextFile) Readdirnames(count int) ([]string, error) {
+	return f.readdirnames(f.ctx, count)
+}
+
+// chunkedIO splits buf into contextIOChunk sized calls to op, checking ctx
+// before each one. op receives the number of bytes already transferred.
+// It stops early on a short transfer, which signals EOF or an error.
+func chunkedIO(ctx context.Context, buf []byte, op func(b []byte, done int64) (int, error)) (int, error) {
+	total := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+
+		chunk := buf[total:min(len(buf), total+contextIOChunk)]
+		n, err := op(chunk, int64(total))
+		total += n
+		if err != nil || n < len(chunk) || total == len(buf) {
+			return total, err
+		}
+	}
+}
@@ -0,0 +1,80 @@
+//go:build cgo && !nocephfs
+
+package cephfs_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"github.com/spf13/afero"
+)
+
+func TestContextFsDeadline(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		tDir := testDir(fs)
+		path := filepath.Join(tDir, testName)
+		if err := afero.WriteFile(fs, path, []byte("content"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+		view := cfs.WithContext(ctx)
+
+		if _, err := view.Stat(path); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%v: Stat = %v, want %v", fs.Name(), err, context.DeadlineExceeded)
+		}
+		if err := view.RemoveAll(tDir); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%v: RemoveAll = %v, want %v", fs.Name(), err, context.DeadlineExceeded)
+		}
+		if _, err := fs.Stat(path); err != nil {
+			t.Errorf("%v: RemoveAll removed files after the deadline: %v", fs.Name(), err)
+		}
+	}
+}
+
+func TestContextFileCancel(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		tDir := testDir(fs)
+		setupTestFiles(t, fs, tDir)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		view := cfs.WithContext(ctx)
+
+		dir, err := view.Open(tDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer dir.Close()
+
+		if _, err := dir.Readdir(1); err != nil {
+			t.Errorf("%v: Readdir before cancel failed: %v", fs.Name(), err)
+		}
+		cancel()
+		if _, err := dir.Readdir(-1); !errors.Is(err, context.Canceled) {
+			t.Errorf("%v: Readdir after cancel = %v, want %v", fs.Name(), err, context.Canceled)
+		}
+
+		err = view.Walk(tDir, func(string, os.FileInfo, error) error { return nil })
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%v: Walk after cancel = %v, want %v", fs.Name(), err, context.Canceled)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+//go:build cgo && !nocephfs
+
+package cephfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	gocephfs "github.com/ceph/go-ceph/cephfs"
+)
+
+const layoutXattr = "ceph.file.layout"
+
+// CopyFile copies the regular file at src to dst within the filesystem,
+// replacing dst if it exists. If the copy fails once dst has been opened,
+// dst is removed. Copying a file onto itself, by the same path or another
+// link to it, fails with syscall.EINVAL and leaves it untouched.
+//
+// go-ceph does not expose a copy offload call, so the data is copied with
+// large ranged reads and writes issued in parallel on the client.
+func (fs *Fs) CopyFile(src, dst string, opts *CopyOptions) (err error) {
+	mount := fs.getMount()
+	defer fs.detectEviction(mount, &err)
+
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+	if src, _, err = normPath("copy", src); err != nil {
+		return err
+	}
+	if dst, _, err = normPath("copy", dst); err != nil {
+		return err
+	}
+	if src == dst {
+		return &os.PathError{Op: "copy", Path: dst, Err: syscall.EINVAL}
+	}
+
+	in, err := mount.Open(src, os.O_RDONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open copy source %s: %w", src, convertErr(err))
+	}
+	defer in.Close()
+
+	stat, err := in.Fstatx(gocephfs.StatxBasicStats, 0)
+	if err != nil {
+		return fmt.Errorf("failed to stat copy source %s: %w", src, err)
+	}
+	if toFileMode(stat.Mode).IsDir() {
+		return &os.PathError{Op: "copy", Path: src, Err: syscall.EISDIR}
+	}
+
+	// dst is truncated only once it is known not to be src
+	out, err := mount.Open(dst, os.O_WRONLY|os.O_CREATE, uint32(stat.Mode&0777))
+	if err != nil {
+		return fmt.Errorf("failed to open copy destination %s: %w", dst, convertErr(err))
+	}
+	if outStat, err := out.Fstatx(gocephfs.StatxIno, 0); err != nil || outStat.Inode == stat.Inode {
+		out.Close()
+		if err == nil {
+			err = syscall.EINVAL
+		}
+		return &os.PathError{Op: "copy", Path: dst, Err: err}
+	}
+	if err := out.Truncate(0); err != nil {
+		out.Close()
+		mount.Unlink(dst)
+		return fmt.Errorf("failed to truncate copy destination %s: %w", dst, err)
+	}
+
+	if err := copyFileContents(mount, in, out, stat, opts); err != nil {
+		out.Close()
+		mount.Unlink(dst)
+		return fmt.Errorf("failed to copy %s to %s: %w", src, dst, err)
+	}
+
+	if err := out.Close(); err != nil {
+		mount.Unlink(dst)
+		return fmt.Errorf("failed to close copy destination %s: %w", dst, err)
+	}
+	return nil
+}
+
+func copyFileContents(mount *gocephfs.MountInfo, in, out *gocephfs.File, stat *gocephfs.CephStatx, opts *CopyOptions) error {
+	if opts.PreserveLayout {
+		layout, err := in.GetXattr(layoutXattr)
+		if err != nil {
+			return fmt.Errorf("failed to read layout: %w", err)
+		}
+		if err := out.SetXattr(layoutXattr, layout, gocephfs.XattrDefault); err != nil {
+			return fmt.Errorf("failed to set layout: %w", err)
+		}
+	}
+
+	if opts.Sparse {
+		// ranges that are skipped must read back as zeros
+		if err := out.Truncate(int64(stat.Size)); err != nil {
+			return fmt.Errorf("failed to size destination: %w", err)
+		}
+	}
+
+	if err := copyRanges(in, out, int64(stat.Size), opts.chunkSize(), opts.parallelism(), opts.Sparse); err != nil {
+		return err
+	}
+
+	if opts.PreserveXattrs {
+		if err := copyXattrs(in, out); err != nil {
+			return err
+		}
+	}
+
+	if opts.PreserveOwner {
+		if err := out.Fchown(stat.Uid, stat.Gid); err != nil {
+			return fmt.Errorf("failed to preserve owner: %w", err)
+		}
+	}
+
+	// chown may clear setuid/setgid bits, so the mode is applied last.
+	if opts.PreserveMode {
+		if err := out.Fchmod(uint32(stat.Mode & 07777)); err != nil {
+			return fmt.Errorf("failed to preserve mode: %w", err)
+		}
+	}
+
+	// the data copy sets the times, so they are applied after it
+	if opts.PreserveTimes {
+		if err := copyTimes(mount, out, stat); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyTimes sets the access and modification times of stat on out.
+func copyTimes(mount *gocephfs.MountInfo, out *gocephfs.File, stat *gocephfs.CephStatx) error {
+	fd, ok := fileDescriptor(out)
+	if !ok {
+		return ErrTimesNotSupported
+	}
+	if err := mount.Futimens(fd, []gocephfs.Timespec{stat.Atime, stat.Mtime}); err != nil {
+		return fmt.Errorf("failed to preserve times: %w", err)
+	}
+	return nil
+}
+
+// copyRanges copies size bytes from in to out, splitting the file into
+// chunk sized ranges that are copied by up to parallel workers. If sparse is
+// set, ranges of zeros are not written.
+func copyRanges(in, out *gocephfs.File, size, chunk int64, parallel int, sparse bool) error {
+	offsets := make(chan int64)
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		done     = make(chan struct{})
+	)
+
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			close(done)
+		})
+	}
+
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, chunk)
+			for off := range offsets {
+				n := min(chunk, size-off)
+				if err := copyRange(in, out, buf[:n], off, sparse); err != nil {
+					fail(err)
+					return
+				}
+			}
+		}()
+	}
+
+feed:
+	for off := int64(0); off < size; off += chunk {
+		select {
+		case offsets <- off:
+		case <-done:
+			break feed
+		}
+	}
+	close(offsets)
+	wg.Wait()
+
+	return firstErr
+}
+
+func copyRange(in, out *gocephfs.File, buf []byte, off int64, sparse bool) error {
+	read := 0
+	for read < len(buf) {
+		n, err := in.ReadAt(buf[read:], off+int64(read))
+		read += n
+		if errors.Is(err, io.EOF) {
+			// the source shrank while copying, copy what is there
+			buf = buf[:read]
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read at offset %d: %w", off+int64(read), err)
+		}
+	}
+
+	if sparse && isZero(buf) {
+		return nil
+	}
+
+	written := 0
+	for written < len(buf) {
+		n, err := out.WriteAt(buf[written:], off+int64(written))
+		if err != nil {
+			return fmt.Errorf("failed to write at offset %d: %w", off+int64(written), err)
+		}
+		if n == 0 {
+			return io.ErrShortWrite
+		}
+		written += n
+	}
+	return nil
+}
+
+func isZero(buf []byte) bool {
+	for _, b := range buf {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func copyXattrs(in, out *gocephfs.File) error {
+	names, err := in.ListXattr()
+	if err != nil {
+		return fmt.Errorf("failed to list xattrs: %w", err)
+	}
+	for _, name := range names {
+		value, err := in.GetXattr(name)
+		if err != nil {
+			return fmt.Errorf("failed to read xattr %s: %w", name, err)
+		}
+		if err := out.SetXattr(name, value, gocephfs.XattrDefault); err != nil {
+			return fmt.Errorf("failed to set xattr %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// CopyTree recursively copies the directory src to dst using CopyFile for
+// every regular file. Symlinks are recreated rather than followed. dst must
+// not exist yet.
+func (fs *Fs) CopyTree(src, dst string, opts *CopyOptions) (err error) {
+	mount := fs.getMount()
+	defer fs.detectEviction(mount, &err)
+
+	if src, _, err = normPath("copy", src); err != nil {
+		return err
+	}
+	if dst, _, err = normPath("copy", dst); err != nil {
+		return err
+	}
+
+	stat, err := mount.Statx(src, gocephfs.StatxBasicStats, gocephfs.AtSymlinkNofollow)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", src, convertErr(err))
+	}
+
+	mode := toFileMode(stat.Mode)
+	switch {
+	case mode&os.ModeSymlink != 0:
+		target, err := mount.Readlink(src)
+		if err != nil {
+			return fmt.Errorf("failed to read link %s: %w", src, err)
+		}
+		if err := mount.Symlink(target, dst); err != nil {
+			return fmt.Errorf("failed to create link %s: %w", dst, convertErr(err))
+		}
+		return nil
+	case !mode.IsDir():
+		return fs.CopyFile(src, dst, opts)
+	}
+
+	if err := fs.Mkdir(dst, mode.Perm()); err != nil {
+		return err
+	}
+
+	dir, err := mount.OpenDir(src)
+	if err != nil {
+		return fmt.Errorf("failed to open dir %s: %w", src, err)
+	}
+	defer dir.Close()
+
+	err = forDirItem(dir, func(de *gocephfs.DirEntry) error {
+		if name := de.Name(); name == "." || name == ".." {
+			return nil
+		}
+		return fs.CopyTree(filepath.Join(src, de.Name()), filepath.Join(dst, de.Name()), opts)
+	})
+	if err != nil {
+		return err
+	}
+
+	if opts != nil && opts.PreserveOwner {
+		if err := fs.Chown(dst, int(stat.Uid), int(stat.Gid)); err != nil {
+			return fmt.Errorf("failed to preserve owner on %s: %w", dst, err)
+		}
+	}
+	if opts != nil && opts.PreserveMode {
+		if err := mount.Chmod(dst, uint32(stat.Mode&07777)); err != nil {
+			return fmt.Errorf("failed to preserve mode on %s: %w", dst, err)
+		}
+	}
+	// copying the entries changed the times of dst, so they are set last
+	if opts != nil && opts.PreserveTimes {
+		out, err := mount.Open(dst, os.O_RDONLY, 0)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", dst, convertErr(err))
+		}
+		defer out.Close()
+		if err := copyTimes(mount, out, stat); err != nil {
+			return fmt.Errorf("failed to preserve times on %s: %w", dst, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+//go:build cgo && !nocephfs
+
+package cephfs_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"github.com/spf13/afero"
+)
+
+func TestCopyFile(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		tDir := testDir(fs)
+		src := filepath.Join(tDir, "src")
+		dst := filepath.Join(tDir, "dst")
+
+		// larger than a few chunks so the ranged copy is exercised
+		data := bytes.Repeat([]byte("0123456789abcdef"), 1<<12)
+		if err := afero.WriteFile(fs, src, data, 0o640); err != nil {
+			t.Fatal(err)
+		}
+
+		opts := &cephfs.CopyOptions{ChunkSize: 4096, Parallelism: 3, PreserveMode: true}
+		if err := cfs.CopyFile(src, dst, opts); err != nil {
+			t.Fatalf("%v: CopyFile failed: %v", fs.Name(), err)
+		}
+
+		got, err := afero.ReadFile(fs, dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("%v: copied data differs, got %d bytes want %d", fs.Name(), len(got), len(data))
+		}
+
+		info, err := fs.Stat(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0o640 {
+			t.Errorf("%v: copied mode = %v, want %v", fs.Name(), info.Mode().Perm(), 0o640)
+		}
+
+		times := filepath.Join(tDir, "times")
+		mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		f, err := fs.OpenFile(src, os.O_RDWR, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = f.(*cephfs.File).Chtimes(mtime, mtime)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%v: Chtimes failed: %v", fs.Name(), err)
+		}
+		if err := cfs.CopyFile(src, times, &cephfs.CopyOptions{PreserveTimes: true}); err != nil {
+			t.Fatalf("%v: CopyFile with PreserveTimes failed: %v", fs.Name(), err)
+		}
+		if info, err := fs.Stat(times); err != nil || !info.ModTime().Equal(mtime) {
+			t.Errorf("%v: copied file = %v, %v, want mtime %v", fs.Name(), info, err, mtime)
+		}
+
+		for _, same := range []string{src, tDir + "/./src"} {
+			if err := cfs.CopyFile(src, same, nil); !errors.Is(err, syscall.EINVAL) {
+				t.Errorf("%v: CopyFile onto itself as %s = %v, want %v", fs.Name(), same, err, syscall.EINVAL)
+			}
+		}
+		if got, err := afero.ReadFile(fs, src); err != nil || !bytes.Equal(got, data) {
+			t.Errorf("%v: CopyFile onto itself changed the source: %d bytes, %v", fs.Name(), len(got), err)
+		}
+	}
+}
+
+func TestCopyTree(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		tDir := testDir(fs)
+		src := filepath.Join(tDir, "src")
+		dst := filepath.Join(tDir, "dst")
+
+		if err := fs.MkdirAll(filepath.Join(src, "a", "b"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		files := map[string]string{
+			"top":   "top level",
+			"a/one": "first",
+			"a/b/c": "nested",
+		}
+		for name, content := range files {
+			if err := afero.WriteFile(fs, filepath.Join(src, name), []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := cfs.CopyTree(src, dst, nil); err != nil {
+			t.Fatalf("%v: CopyTree failed: %v", fs.Name(), err)
+		}
+
+		for name, content := range files {
+			got, err := afero.ReadFile(fs, filepath.Join(dst, name))
+			if err != nil {
+				t.Errorf("%v: reading copied %s: %v", fs.Name(), name, err)
+				continue
+			}
+			if string(got) != content {
+				t.Errorf("%v: copied %s = %q, want %q", fs.Name(), name, got, content)
+			}
+		}
+	}
+}
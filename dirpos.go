@@ -0,0 +1,60 @@
+//go:build cgo && !nocephfs
+
+package cephfs
+
+/*
+#cgo LDFLAGS: -lcephfs
+#cgo CPPFLAGS: -D_FILE_OFFSET_BITS=64
+#include <cephfs/libcephfs.h>
+*/
+import "C"
+
+import (
+	"errors"
+	"reflect"
+	"syscall"
+
+	gocephfs "github.com/ceph/go-ceph/cephfs"
+)
+
+// errDirPosition is returned by tellDir and seekDir if the libcephfs
+// handles of a directory cannot be found, see dirHandles.
+var errDirPosition = errors.New("cephfs: directory positions are not supported by this go-ceph release")
+
+// dirHandles returns the libcephfs handles of mount and dir. go-ceph has no
+// telldir or seekdir and keeps the handles they take in unexported fields,
+// so they are read like fileDescriptor reads the descriptor of a file. It
+// reports false if a go-ceph release stores them differently.
+func dirHandles(mount *gocephfs.MountInfo, dir *gocephfs.Directory) (*C.struct_ceph_mount_info, *C.struct_ceph_dir_result, bool) {
+	cmount := reflect.ValueOf(mount).Elem().FieldByName("mount")
+	cdir := reflect.ValueOf(dir).Elem().FieldByName("dir")
+	if cmount.Kind() != reflect.Pointer || cdir.Kind() != reflect.Pointer || cmount.IsNil() || cdir.IsNil() {
+		return nil, nil, false
+	}
+	return (*C.struct_ceph_mount_info)(cmount.UnsafePointer()), (*C.struct_ceph_dir_result)(cdir.UnsafePointer()), true
+}
+
+// tellDir returns the position of the directory stream of dir. Positions
+// are directory offsets kept by the MDS, so they remain valid in another
+// handle of the same directory, even one of another client.
+func tellDir(mount *gocephfs.MountInfo, dir *gocephfs.Directory) (int64, error) {
+	cmount, cdir, ok := dirHandles(mount, dir)
+	if !ok {
+		return 0, errDirPosition
+	}
+	pos := int64(C.ceph_telldir(cmount, cdir))
+	if pos < 0 {
+		return 0, syscall.Errno(-pos)
+	}
+	return pos, nil
+}
+
+// seekDir moves the directory stream of dir to pos, returned by tellDir.
+func seekDir(mount *gocephfs.MountInfo, dir *gocephfs.Directory, pos int64) error {
+	cmount, cdir, ok := dirHandles(mount, dir)
+	if !ok {
+		return errDirPosition
+	}
+	C.ceph_seekdir(cmount, cdir, C.int64_t(pos))
+	return nil
+}
@@ -0,0 +1,158 @@
+//go:build cgo && !nocephfs
+
+package cephfs
+
+import (
+	iofs "io/fs"
+	"iter"
+	"os"
+	"path/filepath"
+
+	gocephfs "github.com/ceph/go-ceph/cephfs"
+)
+
+// DirEntry is an entry of a directory listed by ReadDirSeq or
+// ReadDirNamesSeq. It implements fs.DirEntry.
+type DirEntry struct {
+	fs    *Fs
+	path  string
+	dtype gocephfs.DType
+	// stat is nil for entries listed by ReadDirNamesSeq
+	stat *gocephfs.CephStatx
+}
+
+// Name returns the name of the entry.
+func (de DirEntry) Name() string {
+	return filepath.Base(de.path)
+}
+
+// Path returns the path of the entry, the listed directory joined with
+// its name.
+func (de DirEntry) Path() string {
+	return de.path
+}
+
+// IsDir reports whether the entry is a directory.
+func (de DirEntry) IsDir() bool {
+	return de.Type().IsDir()
+}
+
+// Type returns the type bits of the entry. It comes from the directory
+// entry itself and costs no stat.
+func (de DirEntry) Type() iofs.FileMode {
+	switch de.dtype {
+	case gocephfs.DTypeDir:
+		return os.ModeDir
+	case gocephfs.DTypeLnk:
+		return os.ModeSymlink
+	case gocephfs.DTypeFIFO:
+		return os.ModeNamedPipe
+	case gocephfs.DTypeSock:
+		return os.ModeSocket
+	case gocephfs.DTypeBlk:
+		return os.ModeDevice
+	case gocephfs.DTypeChr:
+		return os.ModeDevice | os.ModeCharDevice
+	case gocephfs.DTypeUnknown:
+		if de.stat != nil {
+			return toFileMode(de.stat.Mode).Type()
+		}
+	}
+	return 0
+}
+
+// Info returns the FileInfo of the entry. For entries listed by ReadDirSeq
+// it holds the stat read with the entry, limited to the requested statx
+// mask. Entries listed by ReadDirNamesSeq are stat'ed when Info is called,
+// which fails if the entry was removed since.
+func (de DirEntry) Info() (_ iofs.FileInfo, err error) {
+	if de.stat != nil {
+		return &FileInfo{stat: de.stat, path: de.path}, nil
+	}
+	mount := de.fs.getMount()
+	defer de.fs.detectEviction(mount, &err)
+
+	stat, err := mount.Statx(de.path, gocephfs.StatxBasicStats, gocephfs.AtSymlinkNofollow)
+	if err != nil {
+		return nil, &os.PathError{Op: "lstat", Path: de.path, Err: convertErr(err)}
+	}
+	return &FileInfo{stat: stat, path: de.path}, nil
+}
+
+// ReadDirSeq lists the directory at path lazily, reading one entry from
+// the directory stream per iteration, so memory use does not grow with
+// the size of the directory. Each entry carries its basic stats. The
+// iteration stops after the first error.
+func (fs *Fs) ReadDirSeq(path string) iter.Seq2[DirEntry, error] {
+	return fs.ReadDirSeqStatx(path, gocephfs.StatxBasicStats)
+}
+
+// ReadDirSeqStatx is ReadDirSeq with the stat of each entry limited to the
+// fields in mask. A smaller mask needs fewer capabilities from the MDS;
+// fields not in mask are zero in the entry's FileInfo, except the file type
+// which is always filled.
+func (fs *Fs) ReadDirSeqStatx(path string, mask gocephfs.StatxMask) iter.Seq2[DirEntry, error] {
+	return fs.readDirSeq(path, func(dir *gocephfs.Directory) (*gocephfs.DirEntry, *gocephfs.CephStatx, error) {
+		de, err := dir.ReadDirPlus(mask, 0)
+		if de == nil || err != nil {
+			return nil, nil, err
+		}
+		return &de.DirEntry, de.Statx(), nil
+	})
+}
+
+// ReadDirNamesSeq is ReadDirSeq without the stat of each entry, which makes
+// it as cheap as listing a directory can be. The entries still know their
+// type, see DirEntry.Type.
+func (fs *Fs) ReadDirNamesSeq(path string) iter.Seq2[DirEntry, error] {
+	return fs.readDirSeq(path, func(dir *gocephfs.Directory) (*gocephfs.DirEntry, *gocephfs.CephStatx, error) {
+		de, err := dir.ReadDir()
+		return de, nil, err
+	})
+}
+
+type readEntryFunc func(*gocephfs.Directory) (*gocephfs.DirEntry, *gocephfs.CephStatx, error)
+
+func (fs *Fs) readDirSeq(path string, read readEntryFunc) iter.Seq2[DirEntry, error] {
+	return func(yield func(DirEntry, error) bool) {
+		var err error
+		mount := fs.getMount()
+		defer fs.detectEviction(mount, &err)
+
+		path, _, err := normPath("readdirent", path)
+		if err != nil {
+			yield(DirEntry{}, err)
+			return
+		}
+		dir, err := mount.OpenDir(path)
+		if err != nil {
+			yield(DirEntry{}, &os.PathError{Op: "readdirent", Path: path, Err: convertErr(err)})
+			return
+		}
+		defer dir.Close()
+
+		for {
+			var de *gocephfs.DirEntry
+			var stat *gocephfs.CephStatx
+			de, stat, err = read(dir)
+			if err != nil {
+				yield(DirEntry{}, &os.PathError{Op: "readdirent", Path: path, Err: convertErr(err)})
+				return
+			}
+			// de is nil at end of list
+			if de == nil {
+				return
+			}
+
+			// dont list the current dir and parent dir
+			if name := de.Name(); name == "." || name == ".." {
+				continue
+			}
+
+			entry := DirEntry{fs: fs, path: filepath.Join(path, de.Name()), dtype: de.DType(), stat: stat}
+			if !yield(entry, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+//go:build cgo && !nocephfs
+
+package cephfs_test
+
+import (
+	"fmt"
+	"iter"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"github.com/spf13/afero"
+)
+
+func TestReadDirSeq(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		tDir := testDir(fs)
+		if err := fs.Mkdir(filepath.Join(tDir, "sub"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := afero.WriteFile(fs, filepath.Join(tDir, testName), []byte("content"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"sub", testName}
+		sort.Strings(want)
+
+		for name, seq := range map[string]func(string) iter.Seq2[cephfs.DirEntry, error]{
+			"ReadDirSeq":      cfs.ReadDirSeq,
+			"ReadDirNamesSeq": cfs.ReadDirNamesSeq,
+		} {
+			var got []string
+			for de, err := range seq(tDir) {
+				if err != nil {
+					t.Fatalf("%v: %s failed: %v", fs.Name(), name, err)
+				}
+				got = append(got, de.Name())
+
+				if de.IsDir() != (de.Name() == "sub") {
+					t.Errorf("%v: %s: IsDir of %s = %v", fs.Name(), name, de.Name(), de.IsDir())
+				}
+				info, err := de.Info()
+				if err != nil {
+					t.Fatalf("%v: %s: Info failed: %v", fs.Name(), name, err)
+				}
+				if de.Name() == testName && info.Size() != int64(len("content")) {
+					t.Errorf("%v: %s: size of %s = %d", fs.Name(), name, testName, info.Size())
+				}
+			}
+			sort.Strings(got)
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("%v: %s = %v, want %v", fs.Name(), name, got, want)
+			}
+		}
+
+		// stopping early must not leak the directory handle or panic
+		for range cfs.ReadDirSeq(tDir) {
+			break
+		}
+
+		for _, err := range cfs.ReadDirSeq(filepath.Join(tDir, "missing")) {
+			if err == nil {
+				t.Errorf("%v: ReadDirSeq of missing directory did not fail", fs.Name())
+			}
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package cephfs
+
+import (
+	"errors"
+	"syscall"
+)
+
+// errnoOf extracts the errno carried by err. go-ceph errors report the
+// negated return code of the libcephfs call.
+func errnoOf(err error) (syscall.Errno, bool) {
+	var cephErr interface{ ErrorCode() int }
+	if errors.As(err, &cephErr) {
+		code := cephErr.ErrorCode()
+		if code < 0 {
+			code = -code
+		}
+		return syscall.Errno(code), true
+	}
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		return errno, true
+	}
+	return 0, false
+}
@@ -0,0 +1,46 @@
+package cephfs
+
+import (
+	"errors"
+	"syscall"
+)
+
+var (
+	ErrDirDoesntSupport = errors.New("type of Dir does not support this operation")
+	ErrFileNil          = errors.New("cephfs file is nil, is this a directory?")
+	ErrDirNil           = errors.New("cephfs dir is nil, is this a file?")
+)
+
+var ErrAtomicFileDone = errors.New("atomic file was already committed or aborted")
+
+var ErrStaleFile = errors.New("cephfs file handle is stale, the filesystem was remounted after the client was evicted")
+
+// ErrTimesNotSupported is returned when changing file times by path.
+// go-ceph has no path based utime call, only Futimens, which File.Chtimes
+// uses.
+var ErrTimesNotSupported = errors.New("cephfs: setting file times is not supported by go-ceph")
+
+// ErrUnmounted is returned by Ping once the Fs has been unmounted.
+var ErrUnmounted = errors.New("cephfs: filesystem is unmounted")
+
+// ErrInvalidCursor is returned by ReadDirPage for a cursor it did not
+// create.
+var ErrInvalidCursor = errors.New("cephfs: invalid directory cursor")
+
+// ErrCursorStale is returned by ReadDirPage when the directory at the path
+// was replaced since the cursor was created. The listing has to be
+// restarted with an empty cursor.
+var ErrCursorStale = errors.New("cephfs: directory changed since cursor was created")
+
+var ErrInvalidPin = errors.New("invalid mds pin")
+
+// ErrNotInTrash is returned by Restore for paths that are not an entry of
+// the trash.
+var ErrNotInTrash = errors.New("cephfs: not a trash entry")
+
+// IsCrossDevice reports whether err is a rename failing because source and
+// destination are in different quota realms or snapshot hierarchies, which
+// cephfs treats like different filesystems.
+func IsCrossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
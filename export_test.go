@@ -0,0 +1,44 @@
+//go:build cgo && !nocephfs
+
+package cephfs
+
+import (
+	"strconv"
+
+	gocephfs "github.com/ceph/go-ceph/cephfs"
+)
+
+// SimulateEviction handles err as if an operation on the current mount had
+// failed with it.
+func (fs *Fs) SimulateEviction(err error) {
+	mount := fs.getMount()
+	fs.detectEviction(mount, &err)
+}
+
+// SetConfigPath changes the ceph config file mounts are re-created with.
+func (fs *Fs) SetConfigPath(path string) {
+	fs.args.ConfigPath = path
+}
+
+// SetQuota sets the byte quota of the directory at path, making it a quota
+// realm of its own.
+func (fs *Fs) SetQuota(path string, maxBytes uint64) error {
+	mount := fs.getMount()
+	defer fs.putMount(mount)
+	value := []byte(strconv.FormatUint(maxBytes, 10))
+	return mount.SetXattr(path, "ceph.quota.max_bytes", value, gocephfs.XattrDefault)
+}
+
+// SetXattr sets the xattr name of the file at path.
+func (fs *Fs) SetXattr(path, name string, value []byte) error {
+	mount := fs.getMount()
+	defer fs.putMount(mount)
+	return mount.SetXattr(path, name, value, gocephfs.XattrDefault)
+}
+
+// GetXattr returns the xattr name of the file at path.
+func (fs *Fs) GetXattr(path, name string) ([]byte, error) {
+	mount := fs.getMount()
+	defer fs.putMount(mount)
+	return mount.GetXattr(path, name)
+}
@@ -0,0 +1,408 @@
+package cephfs
+
+import (
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// Fault describes a failure FaultyFs injects into matching calls. A fault
+// may delay a call, fail it, cut its I/O short or combine these: a partial
+// write with Err set to ENOSPC transfers part of the buffer and then fails,
+// like a filesystem running full.
+type Fault struct {
+	// Ops limits the fault to these operations, all of them if empty.
+	// Operations are named like the Op of the *os.PathError they return:
+	// open, mkdir, remove, removeall, rename, stat, chmod, chown, chtimes
+	// on the filesystem and read, write, seek, readdir, truncate, sync and
+	// close on files. Create, Open and OpenFile are all "open", ReadAt is
+	// "read" and WriteAt is "write".
+	Ops []string
+	// Path is a filepath.Match pattern the path of the call, or one of its
+	// parent directories, must match. Empty matches every path.
+	Path string
+	// Probability is the chance a matching call is affected. Zero, like
+	// one, affects every matching call.
+	Probability float64
+	// Times is the number of calls the fault affects before it is spent,
+	// zero for no limit.
+	Times int
+
+	// Latency is slept before the call.
+	Latency time.Duration
+	// Err fails the call, wrapped in an *os.PathError for the operation.
+	// Typical values are syscall.EIO, ENOSPC, EDQUOT and ETIMEDOUT.
+	Err error
+	// Partial makes read and write transfer only part of the buffer, and
+	// readdir list only the next entry. A short Read returns no error, the
+	// others return Err or, if it is nil, io.ErrUnexpectedEOF for ReadAt
+	// and readdir and io.ErrShortWrite for writes.
+	Partial bool
+}
+
+func (f *Fault) matches(op, path string) bool {
+	if len(f.Ops) > 0 && !slices.Contains(f.Ops, op) {
+		return false
+	}
+	if f.Path == "" {
+		return true
+	}
+	for p := filepath.Clean(path); ; p = filepath.Dir(p) {
+		if ok, _ := filepath.Match(f.Path, p); ok {
+			return true
+		}
+		if p == filepath.Dir(p) {
+			return false
+		}
+	}
+}
+
+// FaultyFs wraps an afero.Fs and injects the errors, latency and short I/O
+// described by its faults, to test how callers cope with a misbehaving
+// filesystem without a cluster. Random choices come from a generator
+// seeded by NewFaultyFs, so a test making the same calls in the same order
+// sees the same faults every run.
+type FaultyFs struct {
+	base afero.Fs
+
+	mu       sync.Mutex
+	rand     *rand.Rand
+	faults   []Fault
+	fired    []int
+	injected uint64
+}
+
+// NewFaultyFs wraps base. For every call the first matching fault that
+// fires is injected, the others are ignored.
+func NewFaultyFs(base afero.Fs, seed uint64, faults ...Fault) *FaultyFs {
+	ffs := &FaultyFs{base: base, rand: rand.New(rand.NewPCG(seed, seed))}
+	ffs.SetFaults(faults...)
+	return ffs
+}
+
+// SetFaults replaces the faults of ffs, resetting their Times counts. Files
+// already opened through ffs use the new faults.
+func (ffs *FaultyFs) SetFaults(faults ...Fault) {
+	ffs.mu.Lock()
+	defer ffs.mu.Unlock()
+	ffs.faults = slices.Clone(faults)
+	ffs.fired = make([]int, len(faults))
+}
+
+// Injected returns the number of calls a fault has been injected into.
+func (ffs *FaultyFs) Injected() uint64 {
+	ffs.mu.Lock()
+	defer ffs.mu.Unlock()
+	return ffs.injected
+}
+
+// inject picks the fault for a call, sleeps its latency and returns it, or
+// nil if no fault fires.
+func (ffs *FaultyFs) inject(op, path string) *Fault {
+	ffs.mu.Lock()
+	var fault *Fault
+	for i := range ffs.faults {
+		f := &ffs.faults[i]
+		if !f.matches(op, path) || (f.Times > 0 && ffs.fired[i] >= f.Times) {
+			continue
+		}
+		if f.Probability > 0 && f.Probability < 1 && ffs.rand.Float64() >= f.Probability {
+			continue
+		}
+		ffs.fired[i]++
+		ffs.injected++
+		fault = f
+		break
+	}
+	ffs.mu.Unlock()
+
+	if fault == nil {
+		return nil
+	}
+	if fault.Latency > 0 {
+		time.Sleep(fault.Latency)
+	}
+	return fault
+}
+
+// fail returns the error a call fails with, nil if it proceeds.
+func (ffs *FaultyFs) fail(op, path string) error {
+	if fault := ffs.inject(op, path); fault != nil && fault.Err != nil {
+		return &os.PathError{Op: op, Path: path, Err: fault.Err}
+	}
+	return nil
+}
+
+// short returns a random non-empty prefix of buf shorter than buf, or buf
+// if it is too small to shorten.
+func (ffs *FaultyFs) short(buf []byte) []byte {
+	if len(buf) < 2 {
+		return buf
+	}
+	ffs.mu.Lock()
+	defer ffs.mu.Unlock()
+	return buf[:1+ffs.rand.IntN(len(buf)-1)]
+}
+
+func (ffs *FaultyFs) Create(path string) (afero.File, error) {
+	if err := ffs.fail("open", path); err != nil {
+		return nil, err
+	}
+	f, err := ffs.base.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &faultyFile{f, ffs}, nil
+}
+
+func (ffs *FaultyFs) Mkdir(path string, perm os.FileMode) error {
+	if err := ffs.fail("mkdir", path); err != nil {
+		return err
+	}
+	return ffs.base.Mkdir(path, perm)
+}
+
+func (ffs *FaultyFs) MkdirAll(path string, perm os.FileMode) error {
+	if err := ffs.fail("mkdir", path); err != nil {
+		return err
+	}
+	return ffs.base.MkdirAll(path, perm)
+}
+
+func (ffs *FaultyFs) Open(path string) (afero.File, error) {
+	return ffs.OpenFile(path, os.O_RDONLY, 0)
+}
+
+func (ffs *FaultyFs) OpenFile(path string, flag int, perm os.FileMode) (afero.File, error) {
+	if err := ffs.fail("open", path); err != nil {
+		return nil, err
+	}
+	f, err := ffs.base.OpenFile(path, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &faultyFile{f, ffs}, nil
+}
+
+func (ffs *FaultyFs) Remove(path string) error {
+	if err := ffs.fail("remove", path); err != nil {
+		return err
+	}
+	return ffs.base.Remove(path)
+}
+
+func (ffs *FaultyFs) RemoveAll(path string) error {
+	if err := ffs.fail("removeall", path); err != nil {
+		return err
+	}
+	return ffs.base.RemoveAll(path)
+}
+
+// Rename matches faults against oldPath.
+func (ffs *FaultyFs) Rename(oldPath, newPath string) error {
+	if fault := ffs.inject("rename", oldPath); fault != nil && fault.Err != nil {
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: fault.Err}
+	}
+	return ffs.base.Rename(oldPath, newPath)
+}
+
+func (ffs *FaultyFs) Stat(path string) (os.FileInfo, error) {
+	if err := ffs.fail("stat", path); err != nil {
+		return nil, err
+	}
+	return ffs.base.Stat(path)
+}
+
+func (ffs *FaultyFs) Name() string {
+	return ffs.base.Name()
+}
+
+func (ffs *FaultyFs) Chmod(path string, mode os.FileMode) error {
+	if err := ffs.fail("chmod", path); err != nil {
+		return err
+	}
+	return ffs.base.Chmod(path, mode)
+}
+
+func (ffs *FaultyFs) Chown(path string, uid int, gid int) error {
+	if err := ffs.fail("chown", path); err != nil {
+		return err
+	}
+	return ffs.base.Chown(path, uid, gid)
+}
+
+func (ffs *FaultyFs) Chtimes(path string, atime time.Time, mtime time.Time) error {
+	if err := ffs.fail("chtimes", path); err != nil {
+		return err
+	}
+	return ffs.base.Chtimes(path, atime, mtime)
+}
+
+// faultyFile injects the faults of its FaultyFs into calls on a file
+// opened through it.
+type faultyFile struct {
+	afero.File
+	ffs *FaultyFs
+}
+
+// partialErr is the error a partial transfer returns when the wrapped call
+// itself succeeded: the error of fault or, without one, short.
+func (f *faultyFile) partialErr(op string, fault *Fault, short error) error {
+	if fault.Err != nil {
+		return &os.PathError{Op: op, Path: f.Name(), Err: fault.Err}
+	}
+	return short
+}
+
+func (f *faultyFile) Read(buf []byte) (int, error) {
+	fault := f.ffs.inject("read", f.Name())
+	switch {
+	case fault == nil:
+		return f.File.Read(buf)
+	case fault.Partial:
+		n, err := f.File.Read(f.ffs.short(buf))
+		if err == nil && fault.Err != nil {
+			err = &os.PathError{Op: "read", Path: f.Name(), Err: fault.Err}
+		}
+		return n, err
+	case fault.Err != nil:
+		return 0, &os.PathError{Op: "read", Path: f.Name(), Err: fault.Err}
+	}
+	return f.File.Read(buf)
+}
+
+func (f *faultyFile) ReadAt(buf []byte, offset int64) (int, error) {
+	fault := f.ffs.inject("read", f.Name())
+	switch {
+	case fault == nil:
+		return f.File.ReadAt(buf, offset)
+	case fault.Partial:
+		short := f.ffs.short(buf)
+		n, err := f.File.ReadAt(short, offset)
+		if err == nil && len(short) < len(buf) {
+			err = f.partialErr("read", fault, io.ErrUnexpectedEOF)
+		}
+		return n, err
+	case fault.Err != nil:
+		return 0, &os.PathError{Op: "read", Path: f.Name(), Err: fault.Err}
+	}
+	return f.File.ReadAt(buf, offset)
+}
+
+func (f *faultyFile) Write(buf []byte) (int, error) {
+	fault := f.ffs.inject("write", f.Name())
+	switch {
+	case fault == nil:
+		return f.File.Write(buf)
+	case fault.Partial:
+		short := f.ffs.short(buf)
+		n, err := f.File.Write(short)
+		if err == nil && (len(short) < len(buf) || fault.Err != nil) {
+			err = f.partialErr("write", fault, io.ErrShortWrite)
+		}
+		return n, err
+	case fault.Err != nil:
+		return 0, &os.PathError{Op: "write", Path: f.Name(), Err: fault.Err}
+	}
+	return f.File.Write(buf)
+}
+
+func (f *faultyFile) WriteAt(buf []byte, offset int64) (int, error) {
+	fault := f.ffs.inject("write", f.Name())
+	switch {
+	case fault == nil:
+		return f.File.WriteAt(buf, offset)
+	case fault.Partial:
+		short := f.ffs.short(buf)
+		n, err := f.File.WriteAt(short, offset)
+		if err == nil && (len(short) < len(buf) || fault.Err != nil) {
+			err = f.partialErr("write", fault, io.ErrShortWrite)
+		}
+		return n, err
+	case fault.Err != nil:
+		return 0, &os.PathError{Op: "write", Path: f.Name(), Err: fault.Err}
+	}
+	return f.File.WriteAt(buf, offset)
+}
+
+func (f *faultyFile) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+func (f *faultyFile) Seek(offset int64, whence int) (int64, error) {
+	if err := f.ffs.fail("seek", f.Name()); err != nil {
+		return 0, err
+	}
+	return f.File.Seek(offset, whence)
+}
+
+func (f *faultyFile) Readdir(count int) ([]os.FileInfo, error) {
+	fault := f.ffs.inject("readdir", f.Name())
+	switch {
+	case fault == nil:
+		return f.File.Readdir(count)
+	case fault.Partial:
+		list, err := f.File.Readdir(1)
+		if err == nil {
+			err = f.partialErr("readdir", fault, io.ErrUnexpectedEOF)
+		}
+		return list, err
+	case fault.Err != nil:
+		return nil, &os.PathError{Op: "readdir", Path: f.Name(), Err: fault.Err}
+	}
+	return f.File.Readdir(count)
+}
+
+func (f *faultyFile) Readdirnames(count int) ([]string, error) {
+	fault := f.ffs.inject("readdir", f.Name())
+	switch {
+	case fault == nil:
+		return f.File.Readdirnames(count)
+	case fault.Partial:
+		list, err := f.File.Readdirnames(1)
+		if err == nil {
+			err = f.partialErr("readdir", fault, io.ErrUnexpectedEOF)
+		}
+		return list, err
+	case fault.Err != nil:
+		return nil, &os.PathError{Op: "readdir", Path: f.Name(), Err: fault.Err}
+	}
+	return f.File.Readdirnames(count)
+}
+
+func (f *faultyFile) Stat() (os.FileInfo, error) {
+	if err := f.ffs.fail("stat", f.Name()); err != nil {
+		return nil, err
+	}
+	return f.File.Stat()
+}
+
+func (f *faultyFile) Truncate(size int64) error {
+	if err := f.ffs.fail("truncate", f.Name()); err != nil {
+		return err
+	}
+	return f.File.Truncate(size)
+}
+
+func (f *faultyFile) Sync() error {
+	if err := f.ffs.fail("sync", f.Name()); err != nil {
+		return err
+	}
+	return f.File.Sync()
+}
+
+// Close closes the wrapped file even when it injects an error, so a
+// failed Close does not leak it.
+func (f *faultyFile) Close() error {
+	err := f.ffs.fail("close", f.Name())
+	if closeErr := f.File.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,140 @@
+package cephfs_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"testing"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"github.com/spf13/afero"
+)
+
+func TestFaultyFsErrors(t *testing.T) {
+	base := afero.NewMemMapFs()
+	afero.WriteFile(base, "/data/file", []byte("content"), 0o644)
+	afero.WriteFile(base, "/other", []byte("content"), 0o644)
+
+	fs := cephfs.NewFaultyFs(base, 1,
+		cephfs.Fault{Ops: []string{"stat"}, Path: "/data", Err: syscall.EIO},
+		cephfs.Fault{Ops: []string{"write"}, Err: syscall.EDQUOT},
+	)
+
+	_, err := fs.Stat("/data/file")
+	var pe *os.PathError
+	if !errors.As(err, &pe) || pe.Op != "stat" || !errors.Is(err, syscall.EIO) {
+		t.Errorf("Stat in faulty directory = %v, want stat %v", err, syscall.EIO)
+	}
+	if _, err := fs.Stat("/other"); err != nil {
+		t.Errorf("Stat outside faulty directory failed: %v", err)
+	}
+
+	f, err := fs.OpenFile("/other", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Write([]byte("data")); !errors.Is(err, syscall.EDQUOT) {
+		t.Errorf("Write = %v, want %v", err, syscall.EDQUOT)
+	}
+	if got, _ := afero.ReadFile(base, "/other"); string(got) != "content" {
+		t.Errorf("failed write changed the file to %q", got)
+	}
+	if n := fs.Injected(); n != 2 {
+		t.Errorf("Injected = %d, want 2", n)
+	}
+}
+
+func TestFaultyFsTimes(t *testing.T) {
+	base := afero.NewMemMapFs()
+	afero.WriteFile(base, "/file", []byte("content"), 0o644)
+
+	fs := cephfs.NewFaultyFs(base, 1, cephfs.Fault{Err: syscall.ETIMEDOUT, Times: 2})
+	retry := cephfs.NewRetryFs(fs, fastRetries)
+	if _, err := retry.Stat("/file"); err != nil {
+		t.Fatalf("Stat failed after retries: %v", err)
+	}
+	if stats := retry.Stats(); stats.Retries != 2 || stats.Recovered != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	fs.SetFaults(cephfs.Fault{Ops: []string{"open"}, Err: syscall.ENOSPC})
+	if _, err := fs.Create("/new"); !errors.Is(err, syscall.ENOSPC) {
+		t.Errorf("Create = %v, want %v", err, syscall.ENOSPC)
+	}
+}
+
+func TestFaultyFsPartial(t *testing.T) {
+	base := afero.NewMemMapFs()
+	afero.WriteFile(base, "/file", []byte("0123456789"), 0o644)
+
+	fs := cephfs.NewFaultyFs(base, 1, cephfs.Fault{Ops: []string{"read", "write"}, Partial: true})
+	f, err := fs.OpenFile("/file", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	buf := make([]byte, 10)
+	n, err := f.Read(buf)
+	if err != nil || n == 0 || n >= len(buf) {
+		t.Errorf("partial Read = %d, %v", n, err)
+	}
+	n, err = f.ReadAt(buf, 0)
+	if !errors.Is(err, io.ErrUnexpectedEOF) || n >= len(buf) {
+		t.Errorf("partial ReadAt = %d, %v, want short read and %v", n, err, io.ErrUnexpectedEOF)
+	}
+	n, err = f.WriteAt([]byte("abcdef"), 0)
+	if !errors.Is(err, io.ErrShortWrite) || n >= 6 {
+		t.Errorf("partial WriteAt = %d, %v, want short write and %v", n, err, io.ErrShortWrite)
+	}
+
+	fs.SetFaults(cephfs.Fault{Ops: []string{"write"}, Partial: true, Err: syscall.ENOSPC})
+	n, err = f.WriteAt([]byte("abcdef"), 0)
+	if !errors.Is(err, syscall.ENOSPC) || n >= 6 {
+		t.Errorf("partial WriteAt = %d, %v, want short write and %v", n, err, syscall.ENOSPC)
+	}
+
+	afero.WriteFile(base, "/dir/a", nil, 0o644)
+	afero.WriteFile(base, "/dir/b", nil, 0o644)
+	fs.SetFaults(cephfs.Fault{Ops: []string{"readdir"}, Partial: true, Times: 1})
+	dir, err := fs.Open("/dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dir.Close()
+	names, err := dir.Readdirnames(-1)
+	if !errors.Is(err, io.ErrUnexpectedEOF) || len(names) != 1 {
+		t.Fatalf("partial Readdirnames = %v, %v, want one entry and %v", names, err, io.ErrUnexpectedEOF)
+	}
+	if rest, err := dir.Readdirnames(-1); err != nil || len(rest) != 1 || rest[0] == names[0] {
+		t.Errorf("Readdirnames after partial one = %v, %v, want the other entry", rest, err)
+	}
+}
+
+func TestFaultyFsDeterministic(t *testing.T) {
+	run := func(seed uint64) []bool {
+		fs := cephfs.NewFaultyFs(afero.NewMemMapFs(), seed, cephfs.Fault{Err: syscall.EIO, Probability: 0.5})
+		var failed []bool
+		for i := 0; i < 64; i++ {
+			_, err := fs.Stat("/")
+			failed = append(failed, err != nil)
+		}
+		return failed
+	}
+
+	first, second := run(42), run(42)
+	injected := 0
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("call %d differs between runs with the same seed", i)
+		}
+		if first[i] {
+			injected++
+		}
+	}
+	if injected == 0 || injected == len(first) {
+		t.Errorf("%d of %d calls failed with probability 0.5", injected, len(first))
+	}
+}
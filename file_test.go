@@ -0,0 +1,230 @@
+//go:build cgo && !nocephfs
+
+package cephfs_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+// errKind reduces err to what callers of os.File can rely on.
+func errKind(err error) string {
+	switch {
+	case err == nil:
+		return "nil"
+	case errors.Is(err, io.EOF):
+		return "EOF"
+	case errors.Is(err, os.ErrClosed):
+		return "closed"
+	case errors.Is(err, os.ErrNotExist):
+		return "not exist"
+	}
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		return errno.Error()
+	}
+	return "other: " + err.Error()
+}
+
+type fileSemanticsCase struct {
+	name string
+	// run performs the operation on a backend with a directory dir that
+	// contains the regular file file, returning its outcome.
+	run func(fs afero.Fs, dir, file string) string
+	// memMapFsDiffers is set if MemMapFs does not even fail or succeed
+	// like os does. Its errors are never compared in detail, as they do
+	// not wrap errnos.
+	memMapFsDiffers bool
+}
+
+var fileSemanticsCases = []fileSemanticsCase{
+	{
+		name: "read directory",
+		run: func(fs afero.Fs, dir, _ string) string {
+			f, err := fs.Open(dir)
+			if err != nil {
+				return errKind(err)
+			}
+			defer f.Close()
+			_, err = f.Read(make([]byte, 8))
+			return errKind(err)
+		},
+	},
+	{
+		name: "readdir regular file",
+		run: func(fs afero.Fs, _, file string) string {
+			f, err := fs.Open(file)
+			if err != nil {
+				return errKind(err)
+			}
+			defer f.Close()
+			_, err = f.Readdir(-1)
+			return errKind(err)
+		},
+	},
+	{
+		name: "readdirnames regular file",
+		run: func(fs afero.Fs, _, file string) string {
+			f, err := fs.Open(file)
+			if err != nil {
+				return errKind(err)
+			}
+			defer f.Close()
+			_, err = f.Readdirnames(1)
+			return errKind(err)
+		},
+	},
+	{
+		name: "write directory",
+		run: func(fs afero.Fs, dir, _ string) string {
+			f, err := fs.Open(dir)
+			if err != nil {
+				return errKind(err)
+			}
+			defer f.Close()
+			_, err = f.Write([]byte("data"))
+			return errKind(err)
+		},
+	},
+	{
+		name: "write read-only file",
+		run: func(fs afero.Fs, _, file string) string {
+			f, err := fs.Open(file)
+			if err != nil {
+				return errKind(err)
+			}
+			defer f.Close()
+			_, err = f.Write([]byte("data"))
+			return errKind(err)
+		},
+	},
+	{
+		name: "seek rewinds directory",
+		run: func(fs afero.Fs, dir, _ string) string {
+			f, err := fs.Open(dir)
+			if err != nil {
+				return errKind(err)
+			}
+			defer f.Close()
+			first, _ := f.Readdirnames(-1)
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return errKind(err)
+			}
+			second, err := f.Readdirnames(-1)
+			if len(first) != len(second) {
+				return "not rewound"
+			}
+			return errKind(err)
+		},
+		memMapFsDiffers: true,
+	},
+	{
+		name: "read closed file",
+		run: func(fs afero.Fs, _, file string) string {
+			f, err := fs.Open(file)
+			if err != nil {
+				return errKind(err)
+			}
+			f.Close()
+			_, err = f.Read(make([]byte, 8))
+			return errKind(err)
+		},
+	},
+	{
+		name: "open missing file",
+		run: func(fs afero.Fs, dir, _ string) string {
+			_, err := fs.Open(filepath.Join(dir, "missing"))
+			return errKind(err)
+		},
+	},
+	{
+		name: "open directory for writing",
+		run: func(fs afero.Fs, dir, _ string) string {
+			f, err := fs.OpenFile(dir, os.O_WRONLY, 0)
+			if err == nil {
+				f.Close()
+			}
+			return errKind(err)
+		},
+		memMapFsDiffers: true,
+	},
+}
+
+func setupFileSemantics(t *testing.T, fs afero.Fs, dir string) string {
+	file := filepath.Join(dir, testName)
+	if err := afero.WriteFile(fs, file, []byte("content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// TestFileSemantics checks that files of every backend behave like os.File
+// where it matters to callers.
+func TestFileSemantics(t *testing.T) {
+	defer removeAllTestFiles(t)
+
+	osFs := afero.NewOsFs()
+	osDir := t.TempDir()
+	osFile := setupFileSemantics(t, osFs, osDir)
+
+	memFs := afero.NewMemMapFs()
+	memDir := "/semantics"
+	memFs.MkdirAll(memDir, 0o755)
+	memFile := setupFileSemantics(t, memFs, memDir)
+
+	for _, tc := range fileSemanticsCases {
+		want := tc.run(osFs, osDir, osFile)
+
+		if !tc.memMapFsDiffers {
+			if got := tc.run(memFs, memDir, memFile); (got == "nil") != (want == "nil") {
+				t.Errorf("%s: MemMapFs = %s, os = %s", tc.name, got, want)
+			}
+		}
+
+		for _, fs := range Fss {
+			dir := testDir(fs)
+			file := setupFileSemantics(t, fs, dir)
+			if got := tc.run(fs, dir, file); got != want {
+				t.Errorf("%s: %v = %s, os = %s", tc.name, fs.Name(), got, want)
+			}
+		}
+	}
+}
+
+// TestReadAtFull checks that ReadAt fills the buffer across the object
+// boundaries libcephfs may stop a read at, and reports io.EOF with a short
+// read at the end of the file.
+func TestReadAtFull(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		f := tmpFile(fs)
+		defer f.Close()
+
+		// spans two 4MiB objects of the default layout
+		data := make([]byte, 4<<20+4096)
+		for i := range data {
+			data[i] = byte(i)
+		}
+		if _, err := f.Write(data); err != nil {
+			t.Fatal(err)
+		}
+
+		buf := make([]byte, len(data)-1)
+		if n, err := f.ReadAt(buf, 1); n != len(buf) || err != nil {
+			t.Errorf("%v: ReadAt across objects = %d, %v, want %d, nil", fs.Name(), n, err, len(buf))
+		} else if !bytes.Equal(buf, data[1:]) {
+			t.Errorf("%v: ReadAt across objects read the wrong data", fs.Name())
+		}
+
+		if n, err := f.ReadAt(buf[:10], int64(len(data)-4)); n != 4 || err != io.EOF {
+			t.Errorf("%v: ReadAt at end of file = %d, %v, want 4, io.EOF", fs.Name(), n, err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+//go:build cgo && !nocephfs
+
+package cephfs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	gocephfs "github.com/ceph/go-ceph/cephfs"
+)
+
+// Ping performs a cheap round trip to the MDS and OSDs, a statx and a
+// statfs of the root, and returns how long it took.
+//
+// libcephfs calls cannot be interrupted, so the round trip runs in its own
+// goroutine and Ping returns the context's error once it is done. A
+// goroutine stuck on an unhealthy cluster exits when libcephfs gives up.
+func (fs *Fs) Ping(ctx context.Context) (time.Duration, error) {
+	mount, ok := fs.mountedMount()
+	if !ok {
+		return 0, ErrUnmounted
+	}
+	start := time.Now()
+
+	result := make(chan error, 1)
+	go func() {
+		err := ping(mount)
+		fs.detectEviction(mount, &err)
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		return time.Since(start), err
+	case <-ctx.Done():
+		return time.Since(start), ctx.Err()
+	}
+}
+
+func ping(mount *gocephfs.MountInfo) error {
+	if _, err := mount.Statx("/", gocephfs.StatxBasicStats, 0); err != nil {
+		return fmt.Errorf("failed to stat root: %w", err)
+	}
+	if _, err := mount.StatFS("/"); err != nil {
+		return fmt.Errorf("failed to statfs root: %w", err)
+	}
+	return nil
+}
+
+// mountedMount is getMount for an Fs that may have been unmounted, whose
+// mount must not be touched any more. It reports false in that case.
+func (fs *Fs) mountedMount() (*gocephfs.MountInfo, bool) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.unmounted {
+		return nil, false
+	}
+	fs.holdMountLocked(fs.mount)
+	return fs.mount, true
+}
+
+// Health pings the filesystem and reports its state. An unmounted Fs is
+// reported as neither healthy nor mounted.
+func (fs *Fs) Health(ctx context.Context) Health {
+	latency, err := fs.Ping(ctx)
+	health := Health{
+		Healthy:   err == nil,
+		Latency:   latency,
+		CheckedAt: time.Now(),
+	}
+	if err != nil {
+		health.Error = err.Error()
+	}
+
+	mount, ok := fs.mountedMount()
+	if !ok {
+		health.Healthy = false
+		health.Error = ErrUnmounted.Error()
+		return health
+	}
+	defer fs.putMount(mount)
+	health.Mounted = mount.IsMounted()
+
+	if name, err := mount.GetConfigOption("name"); err == nil {
+		health.ClientID = name
+	}
+	if fscid, err := mount.GetFsCid(); err == nil {
+		health.FilesystemID = fscid
+	}
+
+	return health
+}
@@ -0,0 +1,66 @@
+//go:build cgo && !nocephfs
+
+package cephfs_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+)
+
+func TestHealthHandler(t *testing.T) {
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		if _, err := cfs.Ping(context.Background()); err != nil {
+			t.Errorf("%v: Ping failed: %v", fs.Name(), err)
+		}
+
+		rec := httptest.NewRecorder()
+		cfs.HealthHandler(5*time.Second).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("%v: status = %d, want %d", fs.Name(), rec.Code, http.StatusOK)
+		}
+
+		var health cephfs.Health
+		if err := json.NewDecoder(rec.Body).Decode(&health); err != nil {
+			t.Fatal(err)
+		}
+		if !health.Healthy || !health.Mounted || health.ClientID == "" {
+			t.Errorf("%v: unexpected health %+v", fs.Name(), health)
+		}
+	}
+}
+
+func TestHealthAfterUnmount(t *testing.T) {
+	for _, fs := range Fss {
+		if _, ok := fs.(*cephfs.Fs); !ok {
+			continue
+		}
+
+		cfs, err := cephfs.NewCephFS()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cfs.Unmount(); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := cfs.Ping(context.Background()); !errors.Is(err, cephfs.ErrUnmounted) {
+			t.Errorf("Ping after Unmount = %v, want %v", err, cephfs.ErrUnmounted)
+		}
+		if health := cfs.Health(context.Background()); health.Healthy || health.Mounted {
+			t.Errorf("Health after Unmount = %+v", health)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+//go:build cgo && !nocephfs
+
+package cephfs
+
+import (
+	"os"
+	"reflect"
+	"time"
+
+	gocephfs "github.com/ceph/go-ceph/cephfs"
+)
+
+// Chmod changes the mode of the open file. Unlike Fs.Chmod it applies to
+// the file even if it was renamed since it was opened.
+func (f *File) Chmod(mode os.FileMode) (err error) {
+	if err := f.checkFile("chmod"); err != nil {
+		return err
+	}
+	defer f.detectEviction(&err)
+	return f.wrapErr("chmod", f.file.Fchmod(uint32(mode.Perm())))
+}
+
+// Chown changes the uid and gid of the open file.
+func (f *File) Chown(uid, gid int) (err error) {
+	if err := f.checkFile("chown"); err != nil {
+		return err
+	}
+	defer f.detectEviction(&err)
+	return f.wrapErr("chown", f.file.Fchown(uint32(uid), uint32(gid)))
+}
+
+// Chtimes changes the access and modification times of the open file
+// with futimens, so like Chmod it applies even if the file was renamed.
+// It fails with ErrTimesNotSupported if the descriptor cannot be found,
+// see fileDescriptor.
+func (f *File) Chtimes(atime time.Time, mtime time.Time) (err error) {
+	if err := f.checkFile("chtimes"); err != nil {
+		return err
+	}
+	fd, ok := fileDescriptor(f.file)
+	if !ok {
+		return f.wrapErr("chtimes", ErrTimesNotSupported)
+	}
+	defer f.detectEviction(&err)
+	times := []gocephfs.Timespec{toTimespec(atime), toTimespec(mtime)}
+	return f.wrapErr("chtimes", f.mount.Futimens(fd, times))
+}
+
+// fileDescriptor returns the libcephfs descriptor of file, which
+// MountInfo.Futimens takes but go-ceph keeps in an unexported field. It
+// reports false if a go-ceph release stores it differently.
+func fileDescriptor(file *gocephfs.File) (int, bool) {
+	fd := reflect.ValueOf(file).Elem().FieldByName("fd")
+	switch {
+	case !fd.IsValid():
+		return 0, false
+	case fd.CanInt():
+		return int(fd.Int()), true
+	case fd.CanUint():
+		return int(fd.Uint()), true
+	}
+	return 0, false
+}
+
+func toTimespec(t time.Time) gocephfs.Timespec {
+	return gocephfs.Timespec{Sec: t.Unix(), Nsec: int64(t.Nanosecond())}
+}
@@ -0,0 +1,94 @@
+//go:build cgo && !nocephfs
+
+package cephfs_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+)
+
+func TestFileChmodAfterRename(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		tDir := testDir(fs)
+		path := filepath.Join(tDir, testName)
+		renamed := filepath.Join(tDir, "renamed")
+
+		f, err := fs.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		cf, ok := f.(*cephfs.File)
+		if !ok {
+			continue
+		}
+
+		if err := fs.Rename(path, renamed); err != nil {
+			t.Fatal(err)
+		}
+		if err := cf.Chmod(0o600); err != nil {
+			t.Fatalf("%v: Chmod failed: %v", fs.Name(), err)
+		}
+		if err := cf.Chown(os.Getuid(), os.Getgid()); err != nil {
+			t.Errorf("%v: Chown failed: %v", fs.Name(), err)
+		}
+
+		info, err := fs.Stat(renamed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0o600 {
+			t.Errorf("%v: mode after Chmod = %v, want %v", fs.Name(), info.Mode().Perm(), os.FileMode(0o600))
+		}
+
+		mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		if err := cf.Chtimes(mtime, mtime); err != nil {
+			t.Errorf("%v: Chtimes failed: %v", fs.Name(), err)
+		}
+		if info, err := fs.Stat(renamed); err != nil {
+			t.Fatal(err)
+		} else if !info.ModTime().Equal(mtime) {
+			t.Errorf("%v: mtime after Chtimes = %v, want %v", fs.Name(), info.ModTime(), mtime)
+		}
+
+		if err := cf.Truncate(3); err != nil {
+			t.Errorf("%v: Truncate failed: %v", fs.Name(), err)
+		}
+		if info, err := fs.Stat(renamed); err != nil {
+			t.Fatal(err)
+		} else if info.Size() != 3 {
+			t.Errorf("%v: size after Truncate = %d, want 3", fs.Name(), info.Size())
+		}
+		if _, err := fs.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%v: Stat of old name = %v, want %v", fs.Name(), err, os.ErrNotExist)
+		}
+	}
+}
+
+func TestDirStat(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		tDir := testDir(fs)
+
+		dir, err := fs.Open(tDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer dir.Close()
+
+		info, err := dir.Stat()
+		if err != nil {
+			t.Fatalf("%v: Stat on directory handle failed: %v", fs.Name(), err)
+		}
+		if !info.IsDir() {
+			t.Errorf("%v: Stat on directory handle is not a directory", fs.Name())
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package cephfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// Metrics receives the measurements of a MetricsFs. Implementations must
+// be safe for concurrent use. PrometheusMetrics is the built-in one, other
+// metrics libraries can be plugged in by implementing this interface.
+type Metrics interface {
+	// ObserveOp records an operation that took d. errno is the symbolic
+	// name of the errno it failed with, such as "ENOENT", or empty if it
+	// succeeded. Reaching the end of a file or directory is a success.
+	ObserveOp(op, errno string, d time.Duration)
+	// AddBytes records n bytes transferred by a "read" or "write".
+	AddBytes(op string, n int64)
+}
+
+// MetricsFs wraps an afero.Fs and reports every operation on it, and on the
+// files opened through it, to a Metrics. Operations are named like the Op
+// of the *os.PathError they return, see Fault.Ops.
+type MetricsFs struct {
+	base    afero.Fs
+	metrics Metrics
+}
+
+// NewMetricsFs wraps base, reporting to metrics.
+func NewMetricsFs(base afero.Fs, metrics Metrics) *MetricsFs {
+	return &MetricsFs{base: base, metrics: metrics}
+}
+
+func (mfs *MetricsFs) observe(op string, start time.Time, err error) {
+	mfs.metrics.ObserveOp(op, errnoName(err), time.Since(start))
+}
+
+func (mfs *MetricsFs) Create(path string) (afero.File, error) {
+	start := time.Now()
+	f, err := mfs.base.Create(path)
+	mfs.observe("open", start, err)
+	if err != nil {
+		return nil, err
+	}
+	return &metricsFile{f, mfs}, nil
+}
+
+func (mfs *MetricsFs) Mkdir(path string, perm os.FileMode) error {
+	start := time.Now()
+	err := mfs.base.Mkdir(path, perm)
+	mfs.observe("mkdir", start, err)
+	return err
+}
+
+func (mfs *MetricsFs) MkdirAll(path string, perm os.FileMode) error {
+	start := time.Now()
+	err := mfs.base.MkdirAll(path, perm)
+	mfs.observe("mkdir", start, err)
+	return err
+}
+
+func (mfs *MetricsFs) Open(path string) (afero.File, error) {
+	return mfs.OpenFile(path, os.O_RDONLY, 0)
+}
+
+func (mfs *MetricsFs) OpenFile(path string, flag int, perm os.FileMode) (afero.File, error) {
+	start := time.Now()
+	f, err := mfs.base.OpenFile(path, flag, perm)
+	mfs.observe("open", start, err)
+	if err != nil {
+		return nil, err
+	}
+	return &metricsFile{f, mfs}, nil
+}
+
+func (mfs *MetricsFs) Remove(path string) error {
+	start := time.Now()
+	err := mfs.base.Remove(path)
+	mfs.observe("remove", start, err)
+	return err
+}
+
+func (mfs *MetricsFs) RemoveAll(path string) error {
+	start := time.Now()
+	err := mfs.base.RemoveAll(path)
+	mfs.observe("removeall", start, err)
+	return err
+}
+
+func (mfs *MetricsFs) Rename(oldPath, newPath string) error {
+	start := time.Now()
+	err := mfs.base.Rename(oldPath, newPath)
+	mfs.observe("rename", start, err)
+	return err
+}
+
+func (mfs *MetricsFs) Stat(path string) (os.FileInfo, error) {
+	start := time.Now()
+	info, err := mfs.base.Stat(path)
+	mfs.observe("stat", start, err)
+	return info, err
+}
+
+func (mfs *MetricsFs) Name() string {
+	return mfs.base.Name()
+}
+
+func (mfs *MetricsFs) Chmod(path string, mode os.FileMode) error {
+	start := time.Now()
+	err := mfs.base.Chmod(path, mode)
+	mfs.observe("chmod", start, err)
+	return err
+}
+
+func (mfs *MetricsFs) Chown(path string, uid int, gid int) error {
+	start := time.Now()
+	err := mfs.base.Chown(path, uid, gid)
+	mfs.observe("chown", start, err)
+	return err
+}
+
+func (mfs *MetricsFs) Chtimes(path string, atime time.Time, mtime time.Time) error {
+	start := time.Now()
+	err := mfs.base.Chtimes(path, atime, mtime)
+	mfs.observe("chtimes", start, err)
+	return err
+}
+
+// metricsFile reports the operations on a file opened through a MetricsFs.
+type metricsFile struct {
+	afero.File
+	mfs *MetricsFs
+}
+
+func (f *metricsFile) transferred(op string, start time.Time, n int, err error) {
+	f.mfs.observe(op, start, err)
+	if n > 0 {
+		f.mfs.metrics.AddBytes(op, int64(n))
+	}
+}
+
+func (f *metricsFile) Read(buf []byte) (int, error) {
+	start := time.Now()
+	n, err := f.File.Read(buf)
+	f.transferred("read", start, n, err)
+	return n, err
+}
+
+func (f *metricsFile) ReadAt(buf []byte, offset int64) (int, error) {
+	start := time.Now()
+	n, err := f.File.ReadAt(buf, offset)
+	f.transferred("read", start, n, err)
+	return n, err
+}
+
+func (f *metricsFile) Write(buf []byte) (int, error) {
+	start := time.Now()
+	n, err := f.File.Write(buf)
+	f.transferred("write", start, n, err)
+	return n, err
+}
+
+func (f *metricsFile) WriteAt(buf []byte, offset int64) (int, error) {
+	start := time.Now()
+	n, err := f.File.WriteAt(buf, offset)
+	f.transferred("write", start, n, err)
+	return n, err
+}
+
+func (f *metricsFile) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+func (f *metricsFile) Seek(offset int64, whence int) (int64, error) {
+	start := time.Now()
+	pos, err := f.File.Seek(offset, whence)
+	f.mfs.observe("seek", start, err)
+	return pos, err
+}
+
+func (f *metricsFile) Readdir(count int) ([]os.FileInfo, error) {
+	start := time.Now()
+	list, err := f.File.Readdir(count)
+	f.mfs.observe("readdir", start, err)
+	return list, err
+}
+
+func (f *metricsFile) Readdirnames(count int) ([]string, error) {
+	start := time.Now()
+	list, err := f.File.Readdirnames(count)
+	f.mfs.observe("readdir", start, err)
+	return list, err
+}
+
+func (f *metricsFile) Stat() (os.FileInfo, error) {
+	start := time.Now()
+	info, err := f.File.Stat()
+	f.mfs.observe("stat", start, err)
+	return info, err
+}
+
+func (f *metricsFile) Truncate(size int64) error {
+	start := time.Now()
+	err := f.File.Truncate(size)
+	f.mfs.observe("truncate", start, err)
+	return err
+}
+
+func (f *metricsFile) Sync() error {
+	start := time.Now()
+	err := f.File.Sync()
+	f.mfs.observe("sync", start, err)
+	return err
+}
+
+func (f *metricsFile) Close() error {
+	start := time.Now()
+	err := f.File.Close()
+	f.mfs.observe("close", start, err)
+	return err
+}
+
+// errnoNames are the symbolic names of the errnos a filesystem commonly
+// fails with.
+var errnoNames = map[syscall.Errno]string{
+	syscall.EPERM:        "EPERM",
+	syscall.ENOENT:       "ENOENT",
+	syscall.EINTR:        "EINTR",
+	syscall.EIO:          "EIO",
+	syscall.EBADF:        "EBADF",
+	syscall.EAGAIN:       "EAGAIN",
+	syscall.ENOMEM:       "ENOMEM",
+	syscall.EACCES:       "EACCES",
+	syscall.EBUSY:        "EBUSY",
+	syscall.EEXIST:       "EEXIST",
+	syscall.EXDEV:        "EXDEV",
+	syscall.ENOTDIR:      "ENOTDIR",
+	syscall.EISDIR:       "EISDIR",
+	syscall.EINVAL:       "EINVAL",
+	syscall.EFBIG:        "EFBIG",
+	syscall.ENOSPC:       "ENOSPC",
+	syscall.EROFS:        "EROFS",
+	syscall.ERANGE:       "ERANGE",
+	syscall.ENAMETOOLONG: "ENAMETOOLONG",
+	syscall.ENOTEMPTY:    "ENOTEMPTY",
+	syscall.ELOOP:        "ELOOP",
+	syscall.ENODATA:      "ENODATA",
+	syscall.ENOTSUP:      "ENOTSUP",
+	syscall.ESHUTDOWN:    "ESHUTDOWN",
+	syscall.ETIMEDOUT:    "ETIMEDOUT",
+	syscall.ESTALE:       "ESTALE",
+	syscall.EDQUOT:       "EDQUOT",
+}
+
+// errnoName returns the symbolic name of the errno err carries, "" for a
+// nil err or io.EOF, and "other" for errors without an errno.
+func errnoName(err error) string {
+	if err == nil || err == io.EOF {
+		return ""
+	}
+	if errno, ok := errnoOf(err); ok {
+		if name, ok := errnoNames[errno]; ok {
+			return name
+		}
+		return fmt.Sprintf("errno%d", int(errno))
+	}
+	switch {
+	case errors.Is(err, iofs.ErrNotExist):
+		return "ENOENT"
+	case errors.Is(err, iofs.ErrExist):
+		return "EEXIST"
+	case errors.Is(err, iofs.ErrPermission):
+		return "EACCES"
+	case errors.Is(err, iofs.ErrClosed):
+		return "EBADF"
+	}
+	return "other"
+}
@@ -0,0 +1,54 @@
+package cephfs_test
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"github.com/spf13/afero"
+)
+
+func TestMetricsFs(t *testing.T) {
+	faulty := cephfs.NewFaultyFs(afero.NewMemMapFs(), 1,
+		cephfs.Fault{Ops: []string{"stat"}, Path: "/quota", Err: syscall.EDQUOT})
+	metrics := cephfs.NewPrometheusMetrics([]float64{0.5, 1})
+	fs := cephfs.NewMetricsFs(faulty, metrics)
+
+	if err := afero.WriteFile(fs, "/file", []byte("content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := afero.ReadFile(fs, "/file"); err != nil {
+		t.Fatal(err)
+	}
+	fs.Stat("/missing")
+	fs.Stat("/quota")
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	body, _ := io.ReadAll(rec.Body)
+	for _, want := range []string{
+		`cephfs_operations_total{op="open"} 2`,
+		`cephfs_operations_total{op="stat"} 3`,
+		`cephfs_operation_errors_total{op="stat",errno="EDQUOT"} 1`,
+		`cephfs_operation_errors_total{op="stat",errno="ENOENT"} 1`,
+		`cephfs_operation_duration_seconds_bucket{op="stat",le="0.5"} 3`,
+		`cephfs_operation_duration_seconds_bucket{op="stat",le="+Inf"} 3`,
+		`cephfs_operation_duration_seconds_count{op="stat"} 3`,
+		`cephfs_bytes_total{op="read"} 7`,
+		`cephfs_bytes_total{op="write"} 7`,
+		"# TYPE cephfs_operation_duration_seconds histogram",
+	} {
+		if !strings.Contains(string(body), want+"\n") {
+			t.Errorf("exposition is missing %s:\n%s", want, body)
+		}
+	}
+	if strings.Contains(string(body), `op="read",errno`) {
+		t.Errorf("io.EOF was counted as an error:\n%s", body)
+	}
+}
@@ -0,0 +1,444 @@
+//go:build !cgo || nocephfs
+
+package cephfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	iofs "io/fs"
+	"iter"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// ErrNoCephFS is returned by every operation of a package built without
+// cgo or with the nocephfs build tag, which leaves out libcephfs.
+var ErrNoCephFS = errors.New("cephfs: built without CephFS support")
+
+// Fs stands in for the CephFS backend in builds without CephFS support.
+// It implements afero.Fs, failing every operation with ErrNoCephFS, so
+// code choosing between backends at runtime builds either way.
+//
+// The rest of the package API that calls into libcephfs is stubbed the
+// same way: options are accepted and ignored and operations fail with
+// ErrNoCephFS. Plain types, errors and constants are shared with the CephFS
+// build. Only ToAferoFS, WithReaddirStatx and Fs.ReadDirSeqStatx are left
+// out, as their signatures use go-ceph types, which need cgo.
+//
+// There is no fake CephFS backend. Tests that need a filesystem without a
+// cluster can use afero.NewMemMapFs, wrapped in a FaultyFs to inject
+// failures, which both build without cgo.
+type Fs struct{}
+
+// NewCephFS fails with ErrNoCephFS.
+func NewCephFS(opts ...Option) (*Fs, error) {
+	return nil, ErrNoCephFS
+}
+
+func noCephFS(op, path string) error {
+	return &os.PathError{Op: op, Path: path, Err: ErrNoCephFS}
+}
+
+func (fs *Fs) Unmount() error {
+	return ErrNoCephFS
+}
+
+func (fs *Fs) Create(path string) (afero.File, error) {
+	return nil, noCephFS("open", path)
+}
+
+func (fs *Fs) Mkdir(path string, perm os.FileMode) error {
+	return noCephFS("mkdir", path)
+}
+
+func (fs *Fs) MkdirAll(path string, perm os.FileMode) error {
+	return noCephFS("mkdir", path)
+}
+
+func (fs *Fs) Open(path string) (afero.File, error) {
+	return nil, noCephFS("open", path)
+}
+
+func (fs *Fs) OpenFile(path string, flag int, perm os.FileMode) (afero.File, error) {
+	return nil, noCephFS("open", path)
+}
+
+func (fs *Fs) Remove(path string) error {
+	return noCephFS("remove", path)
+}
+
+func (fs *Fs) RemoveAll(path string) error {
+	return noCephFS("RemoveAll", path)
+}
+
+func (fs *Fs) Rename(oldPath, newPath string) error {
+	return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: ErrNoCephFS}
+}
+
+func (fs *Fs) Stat(path string) (os.FileInfo, error) {
+	return nil, noCephFS("stat", path)
+}
+
+func (fs *Fs) Name() string {
+	return "CephFS"
+}
+
+func (fs *Fs) Chmod(path string, mode os.FileMode) error {
+	return noCephFS("chmod", path)
+}
+
+func (fs *Fs) Chown(path string, uid int, gid int) error {
+	return noCephFS("chown", path)
+}
+
+func (fs *Fs) Chtimes(path string, atime time.Time, mtime time.Time) error {
+	return noCephFS("chtimes", path)
+}
+
+// Options
+
+func WithLazyReaddir() Option {
+	return func(*Fs) {}
+}
+
+func WithSortedReaddir(memEntries int) Option {
+	return func(*Fs) {}
+}
+
+func WithSyncOnClose() Option {
+	return func(*Fs) {}
+}
+
+func WithRemountHandler(handler func(RemountEvent)) Option {
+	return func(*Fs) {}
+}
+
+func WithTracer(tracer Tracer) Option {
+	return func(*Fs) {}
+}
+
+// File
+
+type File struct{}
+
+func (f *File) Name() string {
+	return ""
+}
+
+func (f *File) Close() error {
+	return noCephFS("close", "")
+}
+
+func (f *File) Read(buf []byte) (int, error) {
+	return 0, noCephFS("read", "")
+}
+
+func (f *File) ReadAt(buf []byte, offset int64) (int, error) {
+	return 0, noCephFS("read", "")
+}
+
+func (f *File) Write(buf []byte) (int, error) {
+	return 0, noCephFS("write", "")
+}
+
+func (f *File) WriteAt(buf []byte, off int64) (int, error) {
+	return 0, noCephFS("write", "")
+}
+
+func (f *File) WriteString(s string) (int, error) {
+	return 0, noCephFS("write", "")
+}
+
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	return 0, noCephFS("seek", "")
+}
+
+func (f *File) Stat() (os.FileInfo, error) {
+	return nil, noCephFS("stat", "")
+}
+
+func (f *File) Sync() error {
+	return noCephFS("sync", "")
+}
+
+func (f *File) Datasync() error {
+	return noCephFS("datasync", "")
+}
+
+func (f *File) Truncate(size int64) error {
+	return noCephFS("truncate", "")
+}
+
+func (f *File) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, noCephFS("readdirent", "")
+}
+
+func (f *File) Readdirnames(count int) ([]string, error) {
+	return nil, noCephFS("readdirent", "")
+}
+
+func (f *File) Chmod(mode os.FileMode) error {
+	return noCephFS("chmod", "")
+}
+
+func (f *File) Chown(uid, gid int) error {
+	return noCephFS("chown", "")
+}
+
+func (f *File) Chtimes(atime time.Time, mtime time.Time) error {
+	return noCephFS("chtimes", "")
+}
+
+func (f *File) Allocate(off, length int64) error {
+	return noCephFS("fallocate", "")
+}
+
+func (f *File) PunchHole(off, length int64) error {
+	return noCephFS("fallocate", "")
+}
+
+type FileInfo struct{}
+
+func (info *FileInfo) Name() string       { return "" }
+func (info *FileInfo) Size() int64        { return 0 }
+func (info *FileInfo) Mode() os.FileMode  { return 0 }
+func (info *FileInfo) ModTime() time.Time { return time.Time{} }
+func (info *FileInfo) IsDir() bool        { return false }
+func (info *FileInfo) Sys() interface{}   { return nil }
+
+// Listing
+
+type DirEntry struct{}
+
+func (de DirEntry) Name() string                 { return "" }
+func (de DirEntry) Path() string                 { return "" }
+func (de DirEntry) IsDir() bool                  { return false }
+func (de DirEntry) Type() iofs.FileMode          { return 0 }
+func (de DirEntry) Info() (iofs.FileInfo, error) { return nil, noCephFS("stat", "") }
+
+func (fs *Fs) ReadDirSeq(path string) iter.Seq2[DirEntry, error] {
+	return noEntries(path)
+}
+
+func (fs *Fs) ReadDirNamesSeq(path string) iter.Seq2[DirEntry, error] {
+	return noEntries(path)
+}
+
+func noEntries(path string) iter.Seq2[DirEntry, error] {
+	return func(yield func(DirEntry, error) bool) {
+		yield(DirEntry{}, noCephFS("readdirent", path))
+	}
+}
+
+func (fs *Fs) ReadDirPage(path, cursor string, n int) ([]os.FileInfo, string, error) {
+	return nil, "", noCephFS("readdirpage", path)
+}
+
+// Paths and metadata
+
+func (fs *Fs) Chdir(dir string) error {
+	return noCephFS("chdir", dir)
+}
+
+func (fs *Fs) Getwd() (string, error) {
+	return "", ErrNoCephFS
+}
+
+func (fs *Fs) Statfs(path string) (*DiskUsage, error) {
+	return nil, noCephFS("statfs", path)
+}
+
+func (fs *Fs) PinDir(path string, rank int) error {
+	return noCephFS("setpin", path)
+}
+
+func (fs *Fs) PinDistributed(path string, enabled bool) error {
+	return noCephFS("setpin", path)
+}
+
+func (fs *Fs) PinRandom(path string, probability float64) error {
+	return noCephFS("setpin", path)
+}
+
+func (fs *Fs) GetPin(path string) (DirPin, error) {
+	return DirPin{Rank: PinNone}, noCephFS("getpin", path)
+}
+
+// Copying, renaming and removing
+
+func (fs *Fs) CopyFile(src, dst string, opts *CopyOptions) error {
+	return noCephFS("copy", src)
+}
+
+func (fs *Fs) CopyTree(src, dst string, opts *CopyOptions) error {
+	return noCephFS("copy", src)
+}
+
+func (fs *Fs) RenameNoReplace(oldPath, newPath string) error {
+	return fs.Rename(oldPath, newPath)
+}
+
+func (fs *Fs) MoveAcross(oldPath, newPath string, opts *CopyOptions) error {
+	return fs.Rename(oldPath, newPath)
+}
+
+func (fs *Fs) RemoveAllWithOptions(path string, opts *RemoveAllOptions) error {
+	return noCephFS("RemoveAll", path)
+}
+
+// Atomic files
+
+type AtomicFile struct {
+	*File
+}
+
+func (fs *Fs) CreateAtomic(path string, perm os.FileMode, opts *AtomicOptions) (*AtomicFile, error) {
+	return nil, noCephFS("create", path)
+}
+
+func (af *AtomicFile) Target() string {
+	return ""
+}
+
+func (af *AtomicFile) Commit() error {
+	return noCephFS("commit", "")
+}
+
+func (af *AtomicFile) Abort() error {
+	return noCephFS("abort", "")
+}
+
+func (fs *Fs) WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	return noCephFS("create", path)
+}
+
+func (fs *Fs) WriteReaderAtomic(path string, r io.Reader, perm os.FileMode) error {
+	return noCephFS("create", path)
+}
+
+func (fs *Fs) RemoveStaleAtomicFiles(dir string, age time.Duration) ([]string, error) {
+	return nil, noCephFS("open", dir)
+}
+
+// Health and remounts
+
+func (fs *Fs) Ping(ctx context.Context) (time.Duration, error) {
+	return 0, ErrNoCephFS
+}
+
+func (fs *Fs) Health(ctx context.Context) Health {
+	return Health{Error: ErrNoCephFS.Error(), CheckedAt: time.Now()}
+}
+
+// ContextFs
+
+type ContextFs struct {
+	fs  *Fs
+	ctx context.Context
+}
+
+func (fs *Fs) WithContext(ctx context.Context) *ContextFs {
+	return &ContextFs{fs: fs, ctx: ctx}
+}
+
+func (cfs *ContextFs) Context() context.Context {
+	return cfs.ctx
+}
+
+func (cfs *ContextFs) Create(path string) (afero.File, error) {
+	return cfs.fs.Create(path)
+}
+
+func (cfs *ContextFs) Mkdir(path string, perm os.FileMode) error {
+	return cfs.fs.Mkdir(path, perm)
+}
+
+func (cfs *ContextFs) MkdirAll(path string, perm os.FileMode) error {
+	return cfs.fs.MkdirAll(path, perm)
+}
+
+func (cfs *ContextFs) Open(path string) (afero.File, error) {
+	return cfs.fs.Open(path)
+}
+
+func (cfs *ContextFs) OpenFile(path string, flag int, perm os.FileMode) (afero.File, error) {
+	return cfs.fs.OpenFile(path, flag, perm)
+}
+
+func (cfs *ContextFs) Remove(path string) error {
+	return cfs.fs.Remove(path)
+}
+
+func (cfs *ContextFs) RemoveAll(path string) error {
+	return cfs.fs.RemoveAll(path)
+}
+
+func (cfs *ContextFs) Rename(oldPath, newPath string) error {
+	return cfs.fs.Rename(oldPath, newPath)
+}
+
+func (cfs *ContextFs) Stat(path string) (os.FileInfo, error) {
+	return cfs.fs.Stat(path)
+}
+
+func (cfs *ContextFs) Name() string {
+	return cfs.fs.Name()
+}
+
+func (cfs *ContextFs) Chmod(path string, mode os.FileMode) error {
+	return cfs.fs.Chmod(path, mode)
+}
+
+func (cfs *ContextFs) Chown(path string, uid int, gid int) error {
+	return cfs.fs.Chown(path, uid, gid)
+}
+
+func (cfs *ContextFs) Chtimes(path string, atime time.Time, mtime time.Time) error {
+	return cfs.fs.Chtimes(path, atime, mtime)
+}
+
+func (cfs *ContextFs) Walk(root string, walkFn filepath.WalkFunc) error {
+	return walkFn(root, nil, noCephFS("lstat", root))
+}
+
+type ContextFile struct {
+	*File
+}
+
+func (f *ContextFile) Read(buf []byte) (int, error) { return f.File.Read(buf) }
+func (f *ContextFile) ReadAt(buf []byte, offset int64) (int, error) {
+	return f.File.ReadAt(buf, offset)
+}
+func (f *ContextFile) Write(buf []byte) (int, error) { return f.File.Write(buf) }
+func (f *ContextFile) WriteAt(buf []byte, offset int64) (int, error) {
+	return f.File.WriteAt(buf, offset)
+}
+func (f *ContextFile) WriteString(s string) (int, error)        { return f.File.WriteString(s) }
+func (f *ContextFile) Readdir(count int) ([]os.FileInfo, error) { return f.File.Readdir(count) }
+func (f *ContextFile) Readdirnames(count int) ([]string, error) { return f.File.Readdirnames(count) }
+
+// TrashFs
+
+func (tfs *TrashFs) Remove(path string) error {
+	return noCephFS("remove", path)
+}
+
+func (tfs *TrashFs) RemoveAll(path string) error {
+	return noCephFS("removeall", path)
+}
+
+func (tfs *TrashFs) ListTrash() ([]TrashEntry, error) {
+	return nil, noCephFS("readdirent", tfs.root)
+}
+
+func (tfs *TrashFs) Restore(path string) error {
+	return noCephFS("restore", path)
+}
+
+func (tfs *TrashFs) PurgeOlderThan(age time.Duration) ([]string, error) {
+	return nil, noCephFS("readdirent", tfs.root)
+}
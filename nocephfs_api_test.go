@@ -0,0 +1,164 @@
+package cephfs_test
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// noCephFSOmitted are the exported symbols of the CephFS build that the
+// nocephfs build leaves out, because their signatures use go-ceph types.
+var noCephFSOmitted = []string{
+	"func ToAferoFS",
+	"func WithReaddirStatx",
+	"func (*Fs) ReadDirSeqStatx",
+}
+
+// TestNoCephFSAPI checks that nocephfs.go declares every exported symbol
+// of the CephFS build with the same signature, so code written against
+// one builds against the other.
+func TestNoCephFSAPI(t *testing.T) {
+	cephfsAPI := exportedAPI(t, "//go:build cgo && !nocephfs")
+	stubAPI := exportedAPI(t, "//go:build !cgo || nocephfs")
+
+	for name, decl := range cephfsAPI {
+		stub, ok := stubAPI[name]
+		switch {
+		case slices.Contains(noCephFSOmitted, name):
+			if ok {
+				t.Errorf("%s is declared by the nocephfs build, remove it from noCephFSOmitted", name)
+			}
+		case !ok:
+			t.Errorf("%s is missing from the nocephfs build", name)
+		case stub != decl:
+			t.Errorf("%s differs in the nocephfs build:\n%s\nwant\n%s", name, stub, decl)
+		}
+	}
+	for name := range stubAPI {
+		if _, ok := cephfsAPI[name]; !ok && name != "var ErrNoCephFS" {
+			t.Errorf("%s is only declared by the nocephfs build", name)
+		}
+	}
+}
+
+// exportedAPI returns the exported declarations of the non-test files
+// with the build constraint, keyed by kind and name. Values describe the
+// declaration without parameter names and unexported fields.
+func exportedAPI(t *testing.T, constraint string) map[string]string {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fset := token.NewFileSet()
+	api := make(map[string]string)
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(src, []byte(constraint+"\n")) {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if key, ok := funcKey(fset, decl); ok {
+					api[key] = nodeString(fset, stripNames(decl.Type))
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						if spec.Name.IsExported() {
+							api["type "+spec.Name.Name] = typeString(fset, spec.Type)
+						}
+					case *ast.ValueSpec:
+						for _, n := range spec.Names {
+							if n.IsExported() {
+								api[decl.Tok.String()+" "+n.Name] = ""
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return api
+}
+
+func funcKey(fset *token.FileSet, decl *ast.FuncDecl) (string, bool) {
+	if !decl.Name.IsExported() {
+		return "", false
+	}
+	if decl.Recv == nil {
+		return "func " + decl.Name.Name, true
+	}
+	recv := nodeString(fset, decl.Recv.List[0].Type)
+	if !ast.IsExported(strings.TrimPrefix(recv, "*")) {
+		return "", false
+	}
+	return "func (" + recv + ") " + decl.Name.Name, true
+}
+
+func stripNames(ft *ast.FuncType) *ast.FuncType {
+	strip := func(fields *ast.FieldList) *ast.FieldList {
+		if fields == nil {
+			return nil
+		}
+		stripped := &ast.FieldList{}
+		for _, field := range fields.List {
+			for range max(len(field.Names), 1) {
+				stripped.List = append(stripped.List, &ast.Field{Type: field.Type})
+			}
+		}
+		return stripped
+	}
+	return &ast.FuncType{Params: strip(ft.Params), Results: strip(ft.Results)}
+}
+
+// typeString describes a struct by its exported fields, other types by
+// their definition.
+func typeString(fset *token.FileSet, expr ast.Expr) string {
+	st, ok := expr.(*ast.StructType)
+	if !ok {
+		return nodeString(fset, expr)
+	}
+	var fields []string
+	for _, field := range st.Fields.List {
+		var tag string
+		if field.Tag != nil {
+			tag = " " + field.Tag.Value
+		}
+		for _, n := range field.Names {
+			if n.IsExported() {
+				fields = append(fields, n.Name+" "+nodeString(fset, field.Type)+tag)
+			}
+		}
+		if len(field.Names) == 0 {
+			fields = append(fields, nodeString(fset, field.Type))
+		}
+	}
+	return "struct{" + strings.Join(fields, "; ") + "}"
+}
+
+func nodeString(fset *token.FileSet, node any) string {
+	var b bytes.Buffer
+	printer.Fprint(&b, fset, node)
+	return b.String()
+}
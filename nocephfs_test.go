@@ -0,0 +1,24 @@
+//go:build !cgo || nocephfs
+
+package cephfs_test
+
+import (
+	"errors"
+	"testing"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"github.com/spf13/afero"
+)
+
+var _ afero.Fs = (*cephfs.Fs)(nil)
+
+func TestNoCephFS(t *testing.T) {
+	if _, err := cephfs.NewCephFS(); !errors.Is(err, cephfs.ErrNoCephFS) {
+		t.Errorf("NewCephFS = %v, want %v", err, cephfs.ErrNoCephFS)
+	}
+
+	var fs cephfs.Fs
+	if _, err := fs.Stat("/"); !errors.Is(err, cephfs.ErrNoCephFS) {
+		t.Errorf("Stat = %v, want %v", err, cephfs.ErrNoCephFS)
+	}
+}
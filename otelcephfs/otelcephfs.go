@@ -0,0 +1,80 @@
+// Package otelcephfs adapts the tracing hooks of the cephfs package to
+// OpenTelemetry:
+//
+//	fs, err := cephfs.NewCephFS(cephfs.WithTracer(otelcephfs.NewTracer(nil)))
+//
+// Spans are named after the operation, such as "cephfs.stat", and carry
+// the cephfs.Attr attributes. Failed operations record their error and set
+// the span status to Error.
+package otelcephfs
+
+import (
+	"context"
+	"fmt"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the tracer spans are started
+// with.
+const ScopeName = "github.com/crimsonfez/afero-cephfs"
+
+// NewTracer returns a cephfs.Tracer starting spans with a tracer from
+// provider, the global TracerProvider if it is nil.
+func NewTracer(provider trace.TracerProvider) cephfs.Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return &tracer{provider.Tracer(ScopeName)}
+}
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+func (t *tracer) Start(ctx context.Context, op string, attrs ...cephfs.Attribute) (context.Context, cephfs.Span) {
+	ctx, s := t.tracer.Start(ctx, "cephfs."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(convert(attrs)...))
+	return ctx, span{s}
+}
+
+type span struct {
+	span trace.Span
+}
+
+func (s span) SetAttributes(attrs ...cephfs.Attribute) {
+	s.span.SetAttributes(convert(attrs)...)
+}
+
+func (s span) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
+
+func convert(attrs []cephfs.Attribute) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		key := attribute.Key(attr.Key)
+		switch v := attr.Value.(type) {
+		case string:
+			kvs = append(kvs, key.String(v))
+		case int64:
+			kvs = append(kvs, key.Int64(v))
+		case int:
+			kvs = append(kvs, key.Int(v))
+		case bool:
+			kvs = append(kvs, key.Bool(v))
+		default:
+			kvs = append(kvs, key.String(fmt.Sprint(v)))
+		}
+	}
+	return kvs
+}
@@ -0,0 +1,61 @@
+package otelcephfs_test
+
+import (
+	"context"
+	"syscall"
+	"testing"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"github.com/crimsonfez/afero-cephfs/otelcephfs"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := otelcephfs.NewTracer(provider)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	_, span := tracer.Start(ctx, "stat", cephfs.Attribute{Key: cephfs.AttrPath, Value: "/file"})
+	span.SetAttributes(cephfs.Attribute{Key: cephfs.AttrErrno, Value: "ENOENT"})
+	span.End(syscall.ENOENT)
+	_, span = tracer.Start(ctx, "read", cephfs.Attribute{Key: cephfs.AttrPath, Value: "/file"})
+	span.SetAttributes(cephfs.Attribute{Key: cephfs.AttrBytes, Value: int64(7)})
+	span.End(nil)
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("recorded %d spans, want 3", len(spans))
+	}
+	stat, read := spans[0], spans[1]
+	if stat.Name() != "cephfs.stat" || read.Name() != "cephfs.read" {
+		t.Errorf("span names = %q, %q", stat.Name(), read.Name())
+	}
+	if stat.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("span is not a child of the span in the context")
+	}
+	if stat.Status().Code != codes.Error || len(stat.Events()) != 1 {
+		t.Errorf("failed span has status %v and %d events", stat.Status(), len(stat.Events()))
+	}
+	if read.Status().Code != codes.Unset {
+		t.Errorf("successful span has status %v", read.Status())
+	}
+
+	want := map[attribute.Key]attribute.Value{
+		cephfs.AttrPath:  attribute.StringValue("/file"),
+		cephfs.AttrBytes: attribute.Int64Value(7),
+	}
+	for _, kv := range read.Attributes() {
+		if v, ok := want[kv.Key]; ok && v != kv.Value {
+			t.Errorf("attribute %s = %v, want %v", kv.Key, kv.Value.Emit(), v.Emit())
+		}
+		delete(want, kv.Key)
+	}
+	if len(want) > 0 {
+		t.Errorf("missing attributes %v", want)
+	}
+}
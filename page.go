@@ -0,0 +1,121 @@
+//go:build cgo && !nocephfs
+
+package cephfs
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	gocephfs "github.com/ceph/go-ceph/cephfs"
+)
+
+// dirCursor is a position in the stream of a directory, as returned by
+// tellDir, and the inode of the directory it belongs to.
+type dirCursor struct {
+	dir gocephfs.Inode
+	pos int64
+}
+
+func (c dirCursor) String() string {
+	s := fmt.Sprintf("%d:%d", c.dir, c.pos)
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func parseDirCursor(cursor string) (dirCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return dirCursor{}, ErrInvalidCursor
+	}
+	dir, pos, ok := strings.Cut(string(b), ":")
+	if !ok {
+		return dirCursor{}, ErrInvalidCursor
+	}
+	inode, err := strconv.ParseUint(dir, 10, 64)
+	if err != nil {
+		return dirCursor{}, ErrInvalidCursor
+	}
+	offset, err := strconv.ParseInt(pos, 10, 64)
+	if err != nil || offset < 0 {
+		return dirCursor{}, ErrInvalidCursor
+	}
+	return dirCursor{dir: gocephfs.Inode(inode), pos: offset}, nil
+}
+
+// ReadDirPage lists up to n entries of the directory at path, starting at
+// cursor. An empty cursor starts at the beginning of the directory. The
+// returned cursor continues the listing where this page ended and can be
+// used from any Fs mounting the same filesystem. It is empty once the end
+// of the directory has been reached. Entries are statted with the mask set
+// by WithReaddirStatx, like Readdir.
+//
+// The cursor holds the position of the directory stream, which the MDS
+// keeps stable while entries are created and removed, so resuming seeks
+// straight to it and a page costs the same wherever it starts. Entries
+// created or removed after the position are picked up or left out by
+// later pages like with readdir. If the directory at path was replaced by
+// another, ReadDirPage fails with ErrCursorStale.
+func (fs *Fs) ReadDirPage(path, cursor string, n int) (_ []os.FileInfo, next string, err error) {
+	mount := fs.getMount()
+	defer fs.detectEviction(mount, &err)
+
+	if path, _, err = normPath("readdirpage", path); err != nil {
+		return nil, "", err
+	}
+	if n <= 0 {
+		return nil, "", &os.PathError{Op: "readdirpage", Path: path, Err: os.ErrInvalid}
+	}
+
+	var pos dirCursor
+	if cursor != "" {
+		if pos, err = parseDirCursor(cursor); err != nil {
+			return nil, "", &os.PathError{Op: "readdirpage", Path: path, Err: err}
+		}
+	}
+
+	stat, err := mount.Statx(path, gocephfs.StatxIno, 0)
+	if err != nil {
+		return nil, "", pathErr("readdirpage", path, err)
+	}
+	if cursor != "" && stat.Inode != pos.dir {
+		return nil, "", &os.PathError{Op: "readdirpage", Path: path, Err: ErrCursorStale}
+	}
+
+	dir, err := mount.OpenDir(path)
+	if err != nil {
+		return nil, "", pathErr("readdirpage", path, err)
+	}
+	defer dir.Close()
+
+	if cursor != "" {
+		if err := seekDir(mount, dir, pos.pos); err != nil {
+			return nil, "", &os.PathError{Op: "readdirpage", Path: path, Err: err}
+		}
+	}
+
+	list := make([]os.FileInfo, 0, n)
+	for len(list) < n {
+		de, err := dir.ReadDirPlus(fs.readdirMask, 0)
+		if err != nil {
+			return nil, "", pathErr("readdirpage", path, err)
+		}
+		// de is nil at end of list
+		if de == nil {
+			return list, "", nil
+		}
+
+		// dont list the current dir and parent dir
+		if name := de.Name(); name != "." && name != ".." {
+			list = append(list, &FileInfo{stat: de.Statx(), path: filepath.Join(path, name)})
+		}
+	}
+
+	offset, err := tellDir(mount, dir)
+	if err != nil {
+		return nil, "", pathErr("readdirpage", path, err)
+	}
+	return list, dirCursor{dir: stat.Inode, pos: offset}.String(), nil
+}
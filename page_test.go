@@ -0,0 +1,157 @@
+//go:build cgo && !nocephfs
+
+package cephfs_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	gocephfs "github.com/ceph/go-ceph/cephfs"
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"github.com/spf13/afero"
+)
+
+func TestReadDirPage(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		tDir := testDir(fs)
+		var want []string
+		for i := 0; i < 10; i++ {
+			name := fmt.Sprintf("file%d", i)
+			if err := afero.WriteFile(fs, filepath.Join(tDir, name), nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			want = append(want, name)
+		}
+
+		var got []string
+		cursor := ""
+		for pages := 0; ; pages++ {
+			if pages > len(want) {
+				t.Fatalf("%v: listing did not terminate", fs.Name())
+			}
+			list, next, err := cfs.ReadDirPage(tDir, cursor, 3)
+			if err != nil {
+				t.Fatalf("%v: ReadDirPage failed: %v", fs.Name(), err)
+			}
+			if len(list) > 3 {
+				t.Fatalf("%v: ReadDirPage returned %d entries, want at most 3", fs.Name(), len(list))
+			}
+			for _, info := range list {
+				got = append(got, info.Name())
+			}
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+
+		sort.Strings(got)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%v: paged listing = %v, want %v", fs.Name(), got, want)
+		}
+	}
+}
+
+func TestReadDirPageStatx(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		if _, ok := fs.(*cephfs.Fs); !ok {
+			continue
+		}
+
+		tDir := testDir(fs)
+		if err := afero.WriteFile(fs, filepath.Join(tDir, testName), []byte("content"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		maskFs, err := cephfs.NewCephFS(cephfs.WithReaddirStatx(gocephfs.StatxSize))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer maskFs.Unmount()
+
+		list, _, err := maskFs.ReadDirPage(tDir, "", 10)
+		if err != nil {
+			t.Fatalf("%v: ReadDirPage failed: %v", fs.Name(), err)
+		}
+		if len(list) != 1 {
+			t.Fatalf("%v: ReadDirPage returned %d entries, want 1", fs.Name(), len(list))
+		}
+		if list[0].Size() != int64(len("content")) || list[0].IsDir() {
+			t.Errorf("%v: ReadDirPage with a size mask listed size %d, dir %v", fs.Name(), list[0].Size(), list[0].IsDir())
+		}
+		if stat, ok := list[0].Sys().(*gocephfs.CephStatx); !ok || stat.Mask&gocephfs.StatxSize == 0 {
+			t.Errorf("%v: ReadDirPage entry was not statted with the size mask", fs.Name())
+		}
+	}
+}
+
+func TestReadDirPageStaleCursor(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		dir := filepath.Join(testDir(fs), "dir")
+		for i := 0; i < 4; i++ {
+			if err := afero.WriteFile(fs, filepath.Join(dir, fmt.Sprintf("file%d", i)), nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		list, cursor, err := cfs.ReadDirPage(dir, "", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		listed := make(map[string]bool)
+		for _, info := range list {
+			listed[info.Name()] = true
+			if err := fs.Remove(filepath.Join(dir, info.Name())); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// removing listed entries does not move the position
+		rest, next, err := cfs.ReadDirPage(dir, cursor, 2)
+		if err != nil {
+			t.Fatalf("%v: ReadDirPage after removing listed entries failed: %v", fs.Name(), err)
+		}
+		for _, info := range rest {
+			if listed[info.Name()] {
+				t.Errorf("%v: %s listed again", fs.Name(), info.Name())
+			}
+		}
+		if len(rest) != 2 {
+			t.Errorf("%v: second page has %d entries, want 2", fs.Name(), len(rest))
+		}
+		if next != "" {
+			if more, _, err := cfs.ReadDirPage(dir, next, 2); err != nil || len(more) != 0 {
+				t.Errorf("%v: page after the last = %d entries, %v", fs.Name(), len(more), err)
+			}
+		}
+
+		if err := fs.RemoveAll(dir); err != nil {
+			t.Fatal(err)
+		}
+		if err := fs.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := cfs.ReadDirPage(dir, cursor, 2); !errors.Is(err, cephfs.ErrCursorStale) {
+			t.Errorf("%v: ReadDirPage of a replaced directory = %v, want %v", fs.Name(), err, cephfs.ErrCursorStale)
+		}
+		if _, _, err := cfs.ReadDirPage(dir, "not a cursor", 2); !errors.Is(err, cephfs.ErrInvalidCursor) {
+			t.Errorf("%v: ReadDirPage with garbage cursor = %v, want %v", fs.Name(), err, cephfs.ErrInvalidCursor)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+//go:build cgo && !nocephfs
+
+package cephfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	gocephfs "github.com/ceph/go-ceph/cephfs"
+)
+
+// normPath cleans path like filepath.Clean, so "a//b/../c/" and "a/c" name
+// the same file. Relative paths stay relative and resolve against the
+// working directory, see Chdir. An empty path fails with ENOENT like it
+// does for os, instead of naming the working directory as it would for
+// libcephfs. dirOnly reports a trailing slash, with which the path only
+// names a directory.
+func normPath(op, path string) (clean string, dirOnly bool, err error) {
+	if path == "" {
+		return "", false, &os.PathError{Op: op, Path: path, Err: syscall.ENOENT}
+	}
+	clean = filepath.Clean(path)
+	return clean, clean != "/" && strings.HasSuffix(path, "/"), nil
+}
+
+// Chdir changes the working directory that relative paths resolve against.
+// It is a property of the mount, so it is shared by everything using fs,
+// and it is restored when fs remounts after an eviction.
+func (fs *Fs) Chdir(dir string) (err error) {
+	mount := fs.getMount()
+	defer fs.detectEviction(mount, &err)
+
+	dir, _, err = normPath("chdir", dir)
+	if err != nil {
+		return err
+	}
+	if err := mount.ChangeDir(dir); err != nil {
+		return pathErr("chdir", dir, err)
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.cwd = mount.CurrentDir()
+	return nil
+}
+
+// Getwd returns the absolute path of the working directory.
+func (fs *Fs) Getwd() (string, error) {
+	mount := fs.getMount()
+	defer fs.putMount(mount)
+	return mount.CurrentDir(), nil
+}
+
+// restoreCwd changes the working directory of a new mount to cwd, the one
+// set with Chdir on the mount it replaces.
+func restoreCwd(mount *gocephfs.MountInfo, cwd string) error {
+	if cwd == "" {
+		return nil
+	}
+	if err := mount.ChangeDir(cwd); err != nil {
+		return fmt.Errorf("failed to restore working directory %s: %w", cwd, err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+//go:build cgo && !nocephfs
+
+package cephfs_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"github.com/spf13/afero"
+)
+
+func TestPathNormalisation(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		tDir := testDir(fs)
+		file := filepath.Join(tDir, testName)
+		if err := afero.WriteFile(fs, file, []byte("content"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, path := range []string{
+			tDir + "//" + testName,
+			tDir + "/./" + testName,
+			tDir + "/sub/../" + testName,
+		} {
+			if _, err := fs.Stat(path); err != nil {
+				t.Errorf("%v: Stat(%q) failed: %v", fs.Name(), path, err)
+			}
+		}
+
+		if _, err := fs.Stat(""); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%v: Stat of empty path = %v, want %v", fs.Name(), err, os.ErrNotExist)
+		}
+		if _, err := fs.Open(""); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%v: Open of empty path = %v, want %v", fs.Name(), err, os.ErrNotExist)
+		}
+		if err := fs.RemoveAll(""); err != nil {
+			t.Errorf("%v: RemoveAll of empty path = %v, want nil", fs.Name(), err)
+		}
+		if err := fs.RemoveAll(tDir + "/."); !errors.Is(err, syscall.EINVAL) {
+			t.Errorf("%v: RemoveAll of dot path = %v, want %v", fs.Name(), err, syscall.EINVAL)
+		}
+
+		if _, err := fs.Stat(tDir + "/"); err != nil {
+			t.Errorf("%v: Stat of directory with trailing slash failed: %v", fs.Name(), err)
+		}
+		if _, err := fs.Stat(file + "/"); !errors.Is(err, syscall.ENOTDIR) {
+			t.Errorf("%v: Stat of file with trailing slash = %v, want %v", fs.Name(), err, syscall.ENOTDIR)
+		}
+		if _, err := fs.Open(file + "/"); !errors.Is(err, syscall.ENOTDIR) {
+			t.Errorf("%v: Open of file with trailing slash = %v, want %v", fs.Name(), err, syscall.ENOTDIR)
+		}
+
+		if err := fs.RemoveAll(file + "/"); !errors.Is(err, syscall.ENOTDIR) {
+			t.Errorf("%v: RemoveAll of file with trailing slash = %v, want %v", fs.Name(), err, syscall.ENOTDIR)
+		}
+		if err := cfs.CopyFile(tDir+"//"+testName, tDir+"/sub/../copy", nil); err != nil {
+			t.Errorf("%v: CopyFile with unclean paths failed: %v", fs.Name(), err)
+		}
+		if _, err := cfs.CreateAtomic(tDir+"/", 0o644, nil); !errors.Is(err, syscall.EISDIR) {
+			t.Errorf("%v: CreateAtomic with trailing slash = %v, want %v", fs.Name(), err, syscall.EISDIR)
+		}
+		if _, err := cfs.Statfs(""); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%v: Statfs of empty path = %v, want %v", fs.Name(), err, os.ErrNotExist)
+		}
+		if _, err := cfs.GetPin(tDir + "/./"); err != nil {
+			t.Errorf("%v: GetPin with unclean path failed: %v", fs.Name(), err)
+		}
+
+		for de, err := range cfs.ReadDirSeq(tDir + "/") {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(de.Path(), "//") {
+				t.Errorf("%v: listed path %q is not clean", fs.Name(), de.Path())
+			}
+		}
+	}
+}
+
+func TestChdir(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		tDir := testDir(fs)
+		if err := afero.WriteFile(fs, filepath.Join(tDir, testName), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		wd, err := cfs.Getwd()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cfs.Chdir(tDir); err != nil {
+			t.Fatalf("%v: Chdir failed: %v", fs.Name(), err)
+		}
+		defer cfs.Chdir(wd)
+
+		if got, _ := cfs.Getwd(); got != filepath.Join(wd, tDir) {
+			t.Errorf("%v: Getwd = %q, want %q", fs.Name(), got, filepath.Join(wd, tDir))
+		}
+		if _, err := fs.Stat(testName); err != nil {
+			t.Errorf("%v: Stat relative to working directory failed: %v", fs.Name(), err)
+		}
+		if _, err := fs.Stat("."); err != nil {
+			t.Errorf("%v: Stat of . failed: %v", fs.Name(), err)
+		}
+		if info, err := fs.Stat(".."); err != nil || !info.IsDir() {
+			t.Errorf("%v: Stat of .. = %v, %v", fs.Name(), info, err)
+		}
+		if err := cfs.Chdir(testName); !errors.Is(err, syscall.ENOTDIR) {
+			t.Errorf("%v: Chdir to file = %v, want %v", fs.Name(), err, syscall.ENOTDIR)
+		}
+
+		if err := cfs.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fs.Stat(testName); !os.IsNotExist(err) {
+			t.Errorf("%v: Stat relative to restored working directory = %v, want not exist", fs.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+//go:build cgo && !nocephfs
+
+package cephfs
+
+import (
+	"fmt"
+	"strconv"
+	"syscall"
+
+	gocephfs "github.com/ceph/go-ceph/cephfs"
+)
+
+const (
+	pinXattr            = "ceph.dir.pin"
+	pinDistributedXattr = "ceph.dir.pin.distributed"
+	pinRandomXattr      = "ceph.dir.pin.random"
+)
+
+// PinDir pins the directory at path and its descendants to the MDS rank.
+// Pass PinNone to remove the pin.
+func (fs *Fs) PinDir(path string, rank int) error {
+	if rank < PinNone {
+		return fmt.Errorf("%w: rank %d is negative", ErrInvalidPin, rank)
+	}
+	return fs.setPin(path, pinXattr, strconv.Itoa(rank))
+}
+
+// PinDistributed enables or disables ephemeral distributed pinning of the
+// immediate children of the directory at path.
+func (fs *Fs) PinDistributed(path string, enabled bool) error {
+	value := "0"
+	if enabled {
+		value = "1"
+	}
+	return fs.setPin(path, pinDistributedXattr, value)
+}
+
+// PinRandom sets the probability with which descendant directories of path
+// are ephemerally pinned to a random rank. 0 disables random pinning. The
+// MDS rejects values above mds_export_ephemeral_random_max.
+func (fs *Fs) PinRandom(path string, probability float64) error {
+	if probability < 0 || probability > 1 {
+		return fmt.Errorf("%w: probability %v is not within [0, 1]", ErrInvalidPin, probability)
+	}
+	return fs.setPin(path, pinRandomXattr, strconv.FormatFloat(probability, 'f', -1, 64))
+}
+
+// GetPin returns the pinning policy set on the directory at path.
+func (fs *Fs) GetPin(path string) (_ DirPin, err error) {
+	mount := fs.getMount()
+	defer fs.detectEviction(mount, &err)
+
+	pin := DirPin{Rank: PinNone}
+	if path, _, err = normPath("getpin", path); err != nil {
+		return pin, err
+	}
+	if err := checkIsDir(mount, path); err != nil {
+		return pin, err
+	}
+
+	rank, err := getPinXattr(mount, path, pinXattr)
+	if err != nil {
+		return pin, err
+	}
+	if rank != "" {
+		if pin.Rank, err = strconv.Atoi(rank); err != nil {
+			return pin, fmt.Errorf("failed to parse %s %q: %w", pinXattr, rank, err)
+		}
+	}
+
+	distributed, err := getPinXattr(mount, path, pinDistributedXattr)
+	if err != nil {
+		return pin, err
+	}
+	pin.Distributed = distributed == "1"
+
+	random, err := getPinXattr(mount, path, pinRandomXattr)
+	if err != nil {
+		return pin, err
+	}
+	if random != "" {
+		if pin.Random, err = strconv.ParseFloat(random, 64); err != nil {
+			return pin, fmt.Errorf("failed to parse %s %q: %w", pinRandomXattr, random, err)
+		}
+	}
+
+	return pin, nil
+}
+
+func (fs *Fs) setPin(path, name, value string) (err error) {
+	mount := fs.getMount()
+	defer fs.detectEviction(mount, &err)
+
+	if path, _, err = normPath("setpin", path); err != nil {
+		return err
+	}
+	if err := checkIsDir(mount, path); err != nil {
+		return err
+	}
+	if err := mount.SetXattr(path, name, []byte(value), gocephfs.XattrDefault); err != nil {
+		return fmt.Errorf("failed to set %s on %s: %w", name, path, err)
+	}
+	return nil
+}
+
+func checkIsDir(mount *gocephfs.MountInfo, path string) error {
+	stat, err := mount.Statx(path, gocephfs.StatxMode, 0)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, convertErr(err))
+	}
+	if !toFileMode(stat.Mode).IsDir() {
+		return fmt.Errorf("%w: %s is not a directory", ErrInvalidPin, path)
+	}
+	return nil
+}
+
+// getPinXattr reads a pin vxattr, returning "" if it is not set.
+func getPinXattr(mount *gocephfs.MountInfo, path, name string) (string, error) {
+	value, err := mount.GetXattr(path, name)
+	if err != nil {
+		if errno, ok := errnoOf(err); ok && errno == syscall.ENODATA {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get %s on %s: %w", name, path, err)
+	}
+	return trimXattr(value), nil
+}
@@ -0,0 +1,64 @@
+//go:build cgo && !nocephfs
+
+package cephfs_test
+
+import (
+	"errors"
+	"testing"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+)
+
+func TestPinDir(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		tDir := testDir(fs)
+
+		if err := cfs.PinDir(tDir, 0); err != nil {
+			t.Fatalf("%v: PinDir failed: %v", fs.Name(), err)
+		}
+		pin, err := cfs.GetPin(tDir)
+		if err != nil {
+			t.Fatalf("%v: GetPin failed: %v", fs.Name(), err)
+		}
+		if pin.Rank != 0 {
+			t.Errorf("%v: pinned rank = %d, want 0", fs.Name(), pin.Rank)
+		}
+
+		if err := cfs.PinDir(tDir, cephfs.PinNone); err != nil {
+			t.Fatalf("%v: unpinning failed: %v", fs.Name(), err)
+		}
+		if pin, err = cfs.GetPin(tDir); err != nil || pin.Rank != cephfs.PinNone {
+			t.Errorf("%v: GetPin after unpin = %+v, %v", fs.Name(), pin, err)
+		}
+	}
+}
+
+func TestPinValidation(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		tDir := testDir(fs)
+		f := tmpFile(fs)
+		f.Close()
+
+		if err := cfs.PinDir(tDir, -2); !errors.Is(err, cephfs.ErrInvalidPin) {
+			t.Errorf("%v: PinDir(-2) = %v, want %v", fs.Name(), err, cephfs.ErrInvalidPin)
+		}
+		if err := cfs.PinRandom(tDir, 1.5); !errors.Is(err, cephfs.ErrInvalidPin) {
+			t.Errorf("%v: PinRandom(1.5) = %v, want %v", fs.Name(), err, cephfs.ErrInvalidPin)
+		}
+		if err := cfs.PinDir(f.Name(), 0); !errors.Is(err, cephfs.ErrInvalidPin) {
+			t.Errorf("%v: PinDir on a file = %v, want %v", fs.Name(), err, cephfs.ErrInvalidPin)
+		}
+	}
+}
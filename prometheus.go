@@ -0,0 +1,160 @@
+package cephfs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the latency
+// histogram buckets of NewPrometheusMetrics. They range from metadata
+// operations served from the client cache to writes stalled by a busy OSD.
+var DefaultLatencyBuckets = []float64{
+	0.0001, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05,
+	0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+}
+
+// PrometheusMetrics is a Metrics keeping its measurements in memory and
+// exposing them in the Prometheus text format, so they can be scraped
+// without depending on a Prometheus client library. It is an http.Handler
+// serving the exposition, and exposes these metrics:
+//
+//	cephfs_operations_total{op}                  counter
+//	cephfs_operation_errors_total{op,errno}      counter
+//	cephfs_operation_duration_seconds{op}        histogram
+//	cephfs_bytes_total{op}                       counter, op is read or write
+type PrometheusMetrics struct {
+	buckets []float64
+
+	mu    sync.Mutex
+	ops   map[string]*opMetrics
+	bytes map[string]uint64
+}
+
+type opMetrics struct {
+	count  uint64
+	errors map[string]uint64
+	// buckets holds the non-cumulative count of each bucket, the last one
+	// counting observations above the highest bound.
+	buckets []uint64
+	sum     float64
+}
+
+// NewPrometheusMetrics returns an empty PrometheusMetrics with latency
+// histograms using buckets, DefaultLatencyBuckets if it is nil. buckets
+// must be sorted in increasing order.
+func NewPrometheusMetrics(buckets []float64) *PrometheusMetrics {
+	if buckets == nil {
+		buckets = DefaultLatencyBuckets
+	}
+	return &PrometheusMetrics{
+		buckets: slices.Clone(buckets),
+		ops:     make(map[string]*opMetrics),
+		bytes:   make(map[string]uint64),
+	}
+}
+
+func (pm *PrometheusMetrics) ObserveOp(op, errno string, d time.Duration) {
+	seconds := d.Seconds()
+	bucket, _ := slices.BinarySearch(pm.buckets, seconds)
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	m := pm.ops[op]
+	if m == nil {
+		m = &opMetrics{errors: make(map[string]uint64), buckets: make([]uint64, len(pm.buckets)+1)}
+		pm.ops[op] = m
+	}
+	m.count++
+	m.buckets[bucket]++
+	m.sum += seconds
+	if errno != "" {
+		m.errors[errno]++
+	}
+}
+
+func (pm *PrometheusMetrics) AddBytes(op string, n int64) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.bytes[op] += uint64(n)
+}
+
+// WriteTo writes the metrics to w in the Prometheus text format.
+func (pm *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+
+	pm.mu.Lock()
+	ops := slices.Sorted(maps.Keys(pm.ops))
+
+	fmt.Fprintln(cw, "# HELP cephfs_operations_total Number of CephFS operations.")
+	fmt.Fprintln(cw, "# TYPE cephfs_operations_total counter")
+	for _, op := range ops {
+		fmt.Fprintf(cw, "cephfs_operations_total{op=%q} %d\n", op, pm.ops[op].count)
+	}
+
+	fmt.Fprintln(cw, "# HELP cephfs_operation_errors_total Number of failed CephFS operations, by errno.")
+	fmt.Fprintln(cw, "# TYPE cephfs_operation_errors_total counter")
+	for _, op := range ops {
+		errs := pm.ops[op].errors
+		for _, errno := range slices.Sorted(maps.Keys(errs)) {
+			fmt.Fprintf(cw, "cephfs_operation_errors_total{op=%q,errno=%q} %d\n", op, errno, errs[errno])
+		}
+	}
+
+	fmt.Fprintln(cw, "# HELP cephfs_operation_duration_seconds Latency of CephFS operations.")
+	fmt.Fprintln(cw, "# TYPE cephfs_operation_duration_seconds histogram")
+	for _, op := range ops {
+		m := pm.ops[op]
+		var cumulative uint64
+		for i, bound := range pm.buckets {
+			cumulative += m.buckets[i]
+			le := strconv.FormatFloat(bound, 'g', -1, 64)
+			fmt.Fprintf(cw, "cephfs_operation_duration_seconds_bucket{op=%q,le=%q} %d\n", op, le, cumulative)
+		}
+		fmt.Fprintf(cw, "cephfs_operation_duration_seconds_bucket{op=%q,le=\"+Inf\"} %d\n", op, m.count)
+		fmt.Fprintf(cw, "cephfs_operation_duration_seconds_sum{op=%q} %s\n", op, strconv.FormatFloat(m.sum, 'g', -1, 64))
+		fmt.Fprintf(cw, "cephfs_operation_duration_seconds_count{op=%q} %d\n", op, m.count)
+	}
+
+	fmt.Fprintln(cw, "# HELP cephfs_bytes_total Bytes read and written through CephFS.")
+	fmt.Fprintln(cw, "# TYPE cephfs_bytes_total counter")
+	for _, op := range slices.Sorted(maps.Keys(pm.bytes)) {
+		fmt.Fprintf(cw, "cephfs_bytes_total{op=%q} %d\n", op, pm.bytes[op])
+	}
+	pm.mu.Unlock()
+
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	return cw.n, cw.w.Flush()
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format.
+func (pm *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	pm.WriteTo(w)
+}
+
+// countingWriter counts the bytes written to w and keeps the first error,
+// after which it discards all writes.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
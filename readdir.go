@@ -0,0 +1,28 @@
+//go:build cgo && !nocephfs
+
+package cephfs
+
+import gocephfs "github.com/ceph/go-ceph/cephfs"
+
+// WithReaddirStatx sets the statx mask Readdir and ReadDirPage stat entries
+// with, which is gocephfs.StatxBasicStats by default. A smaller mask, such
+// as gocephfs.StatxSize|gocephfs.StatxMtime, needs fewer capabilities from
+// the MDS for large listings. Fields not in mask are zero in the returned
+// FileInfo, except the file type which is always filled. Readdirnames never
+// stats entries.
+func WithReaddirStatx(mask gocephfs.StatxMask) Option {
+	return func(fs *Fs) {
+		fs.readdirMask = mask
+		fs.lazyReaddir = false
+	}
+}
+
+// WithLazyReaddir makes Readdir list entries without stat. Each returned
+// FileInfo stats its file the first time it is asked for more than its name
+// or whether it is a directory, so listings that only look at a few of the
+// entries in detail skip the stat of all others.
+func WithLazyReaddir() Option {
+	return func(fs *Fs) {
+		fs.lazyReaddir = true
+	}
+}
@@ -0,0 +1,122 @@
+//go:build cgo && !nocephfs
+
+package cephfs_test
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	gocephfs "github.com/ceph/go-ceph/cephfs"
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"github.com/spf13/afero"
+)
+
+func TestReaddirStatxOptions(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		if _, ok := fs.(*cephfs.Fs); !ok {
+			continue
+		}
+
+		tDir := testDir(fs)
+		if err := fs.Mkdir(filepath.Join(tDir, "sub"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := afero.WriteFile(fs, filepath.Join(tDir, testName), []byte("content"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		for name, opt := range map[string]cephfs.Option{
+			"WithReaddirStatx": cephfs.WithReaddirStatx(gocephfs.StatxSize | gocephfs.StatxMtime),
+			"WithLazyReaddir":  cephfs.WithLazyReaddir(),
+		} {
+			optFs, err := cephfs.NewCephFS(opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dir, err := optFs.Open(tDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			infos, err := dir.Readdir(-1)
+			dir.Close()
+			if err != nil {
+				t.Fatalf("%v: Readdir %s failed: %v", fs.Name(), name, err)
+			}
+			if len(infos) != 2 {
+				t.Fatalf("%v: Readdir %s returned %d entries, want 2", fs.Name(), name, len(infos))
+			}
+			for _, info := range infos {
+				switch info.Name() {
+				case "sub":
+					if !info.IsDir() {
+						t.Errorf("%v: %s: sub is not a directory", fs.Name(), name)
+					}
+				case testName:
+					if info.IsDir() {
+						t.Errorf("%v: %s: %s is a directory", fs.Name(), name, testName)
+					}
+					if info.Size() != int64(len("content")) {
+						t.Errorf("%v: %s: size = %d, want %d", fs.Name(), name, info.Size(), len("content"))
+					}
+				default:
+					t.Errorf("%v: %s: unexpected entry %s", fs.Name(), name, info.Name())
+				}
+			}
+
+			optFs.Unmount()
+		}
+	}
+}
+
+// TestLazyReaddirConcurrent uses the FileInfos of a lazy Readdir from
+// several goroutines, for the race detector to check.
+func TestLazyReaddirConcurrent(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		if _, ok := fs.(*cephfs.Fs); !ok {
+			continue
+		}
+
+		tDir := testDir(fs)
+		if err := afero.WriteFile(fs, filepath.Join(tDir, testName), []byte("content"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		lazyFs, err := cephfs.NewCephFS(cephfs.WithLazyReaddir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer lazyFs.Unmount()
+
+		dir, err := lazyFs.Open(tDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		infos, err := dir.Readdir(-1)
+		dir.Close()
+		if err != nil || len(infos) != 1 {
+			t.Fatalf("%v: Readdir = %v, %v", fs.Name(), infos, err)
+		}
+
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				if infos[0].IsDir() {
+					t.Errorf("%v: %s is a directory", fs.Name(), testName)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				if size := infos[0].Size(); size != int64(len("content")) {
+					t.Errorf("%v: size = %d, want %d", fs.Name(), size, len("content"))
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
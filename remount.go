@@ -0,0 +1,161 @@
+//go:build cgo && !nocephfs
+
+package cephfs
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+
+	gocephfs "github.com/ceph/go-ceph/cephfs"
+)
+
+// WithRemountHandler sets a callback that is called after every remount
+// attempt. It is called synchronously from the operation that detected the
+// eviction.
+func WithRemountHandler(handler func(RemountEvent)) Option {
+	return func(fs *Fs) {
+		fs.onRemount = handler
+	}
+}
+
+// isEvicted reports whether err means the client lost its session with the
+// cluster. Ceph reports a blocklisted client as ESHUTDOWN.
+func isEvicted(err error) bool {
+	errno, ok := errnoOf(err)
+	return ok && (errno == syscall.ESHUTDOWN || errno == syscall.ENOTCONN)
+}
+
+// getMount returns the current mount and registers the caller as one of
+// its users, so a remount does not release it under the caller. Every call
+// is paired with detectEviction, or putMount.
+func (fs *Fs) getMount() *gocephfs.MountInfo {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.holdMountLocked(fs.mount)
+	return fs.mount
+}
+
+// currentMount returns the current mount without registering a user, for
+// comparisons only.
+func (fs *Fs) currentMount() *gocephfs.MountInfo {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	return fs.mount
+}
+
+// holdMount registers another user of mount, which the caller already
+// holds. Files hold the mount they were opened on until they are closed.
+func (fs *Fs) holdMount(mount *gocephfs.MountInfo) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.holdMountLocked(mount)
+}
+
+func (fs *Fs) holdMountLocked(mount *gocephfs.MountInfo) {
+	if fs.mountUsers == nil {
+		fs.mountUsers = make(map[*gocephfs.MountInfo]int)
+	}
+	fs.mountUsers[mount]++
+}
+
+// putMount unregisters a user of mount. The last user of a mount replaced
+// by a remount, or of the mount of an unmounted Fs, releases it.
+func (fs *Fs) putMount(mount *gocephfs.MountInfo) {
+	fs.mu.Lock()
+	fs.mountUsers[mount]--
+	release := fs.mountUsers[mount] == 0 && (mount != fs.mount || fs.unmounted)
+	if fs.mountUsers[mount] == 0 {
+		delete(fs.mountUsers, mount)
+	}
+	fs.mu.Unlock()
+
+	if release {
+		releaseMount(mount)
+	}
+}
+
+// releaseMount tears down a mount replaced after an eviction, where errors
+// are expected as its session is gone, or one left in use by Unmount.
+func releaseMount(mount *gocephfs.MountInfo) {
+	_ = mount.Unmount()
+	_ = mount.Release()
+}
+
+// detectEviction re-creates the mount if *errp shows that mount was
+// evicted, and unregisters the caller as a user of mount. It is deferred
+// by every operation with the mount the operation used, so concurrent
+// failures on the same mount only remount once.
+func (fs *Fs) detectEviction(mount *gocephfs.MountInfo, errp *error) {
+	fs.detectEvictionHeld(mount, errp)
+	fs.putMount(mount)
+}
+
+// detectEvictionHeld is detectEviction for a mount the caller keeps
+// holding, like the mount of a File.
+func (fs *Fs) detectEvictionHeld(mount *gocephfs.MountInfo, errp *error) {
+	if *errp == nil || fs.args == nil || !isEvicted(*errp) {
+		return
+	}
+	fs.remount(mount, *errp)
+}
+
+func (fs *Fs) remount(stale *gocephfs.MountInfo, cause error) {
+	fs.mu.Lock()
+	if fs.mount != stale || fs.unmounted || fs.remounting {
+		// already remounted or being remounted by another operation, or
+		// unmounted on purpose
+		fs.mu.Unlock()
+		return
+	}
+	fs.remounting = true
+	cwd := fs.cwd
+	fs.mu.Unlock()
+
+	// The new mount is created without holding mu, so operations are not
+	// blocked while it connects; until it is swapped in they keep failing
+	// on the stale mount. The stale mount is only replaced once a new one
+	// exists, so a failed attempt leaves a mount that fails with the same
+	// error and triggers the next attempt.
+	mount, err := createMount(*fs.args)
+	var cwdErr error
+	if err == nil {
+		cwdErr = restoreCwd(mount, cwd)
+	}
+
+	fs.mu.Lock()
+	fs.remounting = false
+	if err == nil && fs.unmounted {
+		// unmounted while connecting, the new mount is not wanted
+		fs.mu.Unlock()
+		releaseMount(mount)
+		return
+	}
+	// The stale mount is released by its last user, as operations and
+	// open files on other goroutines may still be using it.
+	release := false
+	if err == nil {
+		fs.mount = mount
+		err = cwdErr
+		release = fs.mountUsers[stale] == 0
+	}
+	handler := fs.onRemount
+	fs.mu.Unlock()
+
+	if release {
+		releaseMount(stale)
+	}
+
+	if handler != nil {
+		if err != nil {
+			err = fmt.Errorf("failed to remount cephfs: %w", err)
+		}
+		handler(RemountEvent{Cause: cause, Err: err, Time: time.Now()})
+	}
+}
+
+// stale reports whether the file was opened on a mount that has since been
+// replaced.
+func (f *File) stale() bool {
+	return f.fs != nil && f.fs.currentMount() != f.mount
+}
@@ -0,0 +1,115 @@
+//go:build cgo && !nocephfs
+
+package cephfs_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"github.com/spf13/afero"
+)
+
+func TestRemountAfterEviction(t *testing.T) {
+	for _, fs := range Fss {
+		if _, ok := fs.(*cephfs.Fs); ok {
+			testRemountAfterEviction(t)
+		}
+	}
+}
+
+func testRemountAfterEviction(t *testing.T) {
+	var events []cephfs.RemountEvent
+	fs, err := cephfs.NewCephFS(cephfs.WithRemountHandler(func(ev cephfs.RemountEvent) {
+		events = append(events, ev)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Unmount()
+	defer removeAllTestFiles(t)
+
+	path := filepath.Join("/"+testDir(fs), testName)
+	if err := afero.WriteFile(fs, path, []byte("content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := fs.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a failed attempt keeps the old mount usable
+	fs.SetConfigPath("/nonexistent/ceph.conf")
+	fs.SimulateEviction(syscall.ESHUTDOWN)
+	if len(events) != 1 || events[0].Err == nil || !errors.Is(events[0].Cause, syscall.ESHUTDOWN) {
+		t.Fatalf("events after failed remount = %+v", events)
+	}
+	if _, err := fs.Stat(path); err != nil {
+		t.Errorf("Stat after failed remount: %v", err)
+	}
+	if _, err := f.Read(make([]byte, 1)); err != nil {
+		t.Errorf("Read after failed remount: %v", err)
+	}
+
+	// a successful one replaces it, files opened on it become stale but
+	// are still safe to use and close
+	fs.SetConfigPath("")
+	fs.SimulateEviction(syscall.ENOTCONN)
+	if len(events) != 2 || events[1].Err != nil {
+		t.Fatalf("events after remount = %+v", events)
+	}
+	if _, err := f.Read(make([]byte, 1)); !errors.Is(err, cephfs.ErrStaleFile) {
+		t.Errorf("Read of stale file = %v, want %v", err, cephfs.ErrStaleFile)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("Close of stale file: %v", err)
+	}
+	if _, err := fs.Stat(path); err != nil {
+		t.Errorf("Stat after remount: %v", err)
+	}
+
+	fs.SimulateEviction(os.ErrNotExist)
+	if len(events) != 2 {
+		t.Errorf("remounted on an error that is not an eviction")
+	}
+}
+
+func TestUnmountWithOpenFile(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, base := range Fss {
+		if _, ok := base.(*cephfs.Fs); !ok {
+			continue
+		}
+
+		path := filepath.Join("/"+testDir(base), testName)
+		if err := afero.WriteFile(base, path, []byte("content"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		fs, err := cephfs.NewCephFS()
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := fs.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the mount is released once the file is closed
+		if err := fs.Unmount(); err != nil {
+			t.Fatalf("Unmount with an open file failed: %v", err)
+		}
+		if data, err := afero.ReadAll(f); err != nil || string(data) != "content" {
+			t.Errorf("read after Unmount = %q, %v", data, err)
+		}
+		if err := f.Close(); err != nil {
+			t.Errorf("Close after Unmount: %v", err)
+		}
+		if err := fs.Unmount(); err != nil {
+			t.Errorf("second Unmount = %v, want nil", err)
+		}
+	}
+}
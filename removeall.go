@@ -0,0 +1,238 @@
+//go:build cgo && !nocephfs
+
+package cephfs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	gocephfs "github.com/ceph/go-ceph/cephfs"
+)
+
+// RemoveAll removes a directory path and any children it contains. It
+// does not fail if the path does not exist (return nil). Symlinks are
+// removed, never followed. See RemoveAllWithOptions for how failures are
+// handled.
+func (fs *Fs) RemoveAll(path string) error {
+	return fs.removeAll(context.Background(), path, nil)
+}
+
+// RemoveAllWithOptions is RemoveAll with options, nil for the defaults.
+//
+// The tree is removed without recursion: directories are listed level by
+// level, each directory is closed before its files are unlinked, and the
+// emptied directories are removed deepest first. Failing to remove a path
+// does not stop the removal of the others. If any path could not be
+// removed, a *RemoveAllError listing all of them is returned.
+func (fs *Fs) RemoveAllWithOptions(path string, opts *RemoveAllOptions) error {
+	return fs.removeAll(context.Background(), path, opts)
+}
+
+func (fs *Fs) removeAll(ctx context.Context, path string, opts *RemoveAllOptions) (err error) {
+	ctx, span := fs.startSpan(ctx, "removeall", Attribute{AttrPath, path})
+	defer func() { span.end(err) }()
+	mount := fs.getMount()
+	defer fs.detectEviction(mount, &err)
+
+	if opts == nil {
+		opts = &RemoveAllOptions{}
+	}
+	r := &remover{ctx: ctx, mount: mount, opts: opts}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// like os.RemoveAll, do nothing for an empty path and refuse to remove
+	// the working directory or its parent
+	if path == "" {
+		return nil
+	}
+	if base := filepath.Base(path); base == "." || base == ".." {
+		return &os.PathError{Op: "RemoveAll", Path: path, Err: syscall.EINVAL}
+	}
+	path, dirOnly, err := normPath("RemoveAll", path)
+	if err != nil {
+		return err
+	}
+
+	stat, err := mount.Statx(path, gocephfs.StatxBasicStats, gocephfs.AtSymlinkNofollow)
+	if err != nil {
+		if errors.Is(err, gocephfs.ErrNotExist) {
+			return nil
+		}
+		return pathErr("lstat", path, err)
+	}
+	if !toFileMode(stat.Mode).IsDir() {
+		if dirOnly {
+			return &os.PathError{Op: "RemoveAll", Path: path + "/", Err: syscall.ENOTDIR}
+		}
+		if err := mount.Unlink(path); err != nil {
+			return pathErr("unlink", path, err)
+		}
+		r.removed(path)
+		return nil
+	}
+
+	// list and empty the tree level by level, remembering the directories
+	var levels [][]string
+	for level := []string{path}; len(level) > 0; {
+		levels = append(levels, level)
+		var next []string
+		var mu sync.Mutex
+		r.each(level, func(dir string) {
+			subdirs := r.emptyDir(dir)
+			mu.Lock()
+			next = append(next, subdirs...)
+			mu.Unlock()
+		})
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		level = next
+	}
+
+	for i := len(levels) - 1; i >= 0; i-- {
+		r.each(levels[i], func(dir string) {
+			if err := mount.RemoveDir(dir); err != nil {
+				r.fail(pathErr("rmdir", dir, err))
+				return
+			}
+			r.removed(dir)
+		})
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+
+	if len(r.errs) > 0 {
+		return &RemoveAllError{Errs: r.errs}
+	}
+	return nil
+}
+
+// remover holds the state of one RemoveAll call.
+type remover struct {
+	ctx   context.Context
+	mount *gocephfs.MountInfo
+	opts  *RemoveAllOptions
+
+	mu    sync.Mutex
+	errs  []error
+	count int64
+}
+
+// each calls fn for every path, running up to Parallelism calls at once.
+func (r *remover) each(paths []string, fn func(string)) {
+	parallel := max(r.opts.Parallelism, 1)
+	if parallel == 1 || len(paths) == 1 {
+		for _, path := range paths {
+			if r.ctx.Err() != nil {
+				return
+			}
+			fn(path)
+		}
+		return
+	}
+
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < min(parallel, len(paths)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range work {
+				fn(path)
+			}
+		}()
+	}
+	for _, path := range paths {
+		if r.ctx.Err() != nil {
+			break
+		}
+		work <- path
+	}
+	close(work)
+	wg.Wait()
+}
+
+// emptyDir lists dir, closes it and unlinks everything in it that is not a
+// directory. It returns the subdirectories.
+func (r *remover) emptyDir(dir string) []string {
+	d, err := r.mount.OpenDir(dir)
+	if err != nil {
+		r.fail(pathErr("open", dir, err))
+		return nil
+	}
+	var entries []*gocephfs.DirEntry
+	err = forDirItem(d, func(de *gocephfs.DirEntry) error {
+		if name := de.Name(); name != "." && name != ".." {
+			entries = append(entries, de)
+		}
+		return nil
+	})
+	d.Close()
+	if err != nil {
+		r.fail(pathErr("readdirent", dir, err))
+	}
+
+	var subdirs []string
+	for _, de := range entries {
+		if r.ctx.Err() != nil {
+			return nil
+		}
+		fullPath := filepath.Join(dir, de.Name())
+
+		isDir := de.DType() == gocephfs.DTypeDir
+		if de.DType() == gocephfs.DTypeUnknown {
+			stat, err := r.mount.Statx(fullPath, gocephfs.StatxBasicStats, gocephfs.AtSymlinkNofollow)
+			if err != nil {
+				if !errors.Is(err, gocephfs.ErrNotExist) {
+					r.fail(pathErr("lstat", fullPath, err))
+				}
+				continue
+			}
+			isDir = toFileMode(stat.Mode).IsDir()
+		}
+
+		if isDir {
+			subdirs = append(subdirs, fullPath)
+			continue
+		}
+		if err := r.mount.Unlink(fullPath); err != nil {
+			if !errors.Is(err, gocephfs.ErrNotExist) {
+				r.fail(pathErr("unlink", fullPath, err))
+			}
+			continue
+		}
+		r.removed(fullPath)
+	}
+	return subdirs
+}
+
+func (r *remover) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errs = append(r.errs, err)
+}
+
+func (r *remover) removed(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.count++
+	if r.opts.Progress != nil {
+		r.opts.Progress(path, r.count)
+	}
+}
+
+// pathErr wraps an error of op on path the way os does.
+func pathErr(op, path string, err error) error {
+	if errno, ok := errnoOf(err); ok {
+		err = errno
+	}
+	return &os.PathError{Op: op, Path: path, Err: err}
+}
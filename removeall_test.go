@@ -0,0 +1,64 @@
+//go:build cgo && !nocephfs
+
+package cephfs_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"github.com/spf13/afero"
+)
+
+func TestRemoveAllWithOptions(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		tDir := testDir(fs)
+		root := filepath.Join(tDir, "tree")
+		entries := 1
+		for i := 0; i < 3; i++ {
+			dir := filepath.Join(root, fmt.Sprintf("dir%d", i), "nested")
+			if err := fs.MkdirAll(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			entries += 2
+			for j := 0; j < 3; j++ {
+				if err := afero.WriteFile(fs, filepath.Join(dir, fmt.Sprintf("file%d", j)), nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				entries++
+			}
+		}
+
+		var last int64
+		err := cfs.RemoveAllWithOptions(root, &cephfs.RemoveAllOptions{
+			Parallelism: 4,
+			Progress: func(path string, removed int64) {
+				if removed != last+1 {
+					t.Errorf("%v: progress jumped from %d to %d", fs.Name(), last, removed)
+				}
+				last = removed
+			},
+		})
+		if err != nil {
+			t.Fatalf("%v: RemoveAllWithOptions failed: %v", fs.Name(), err)
+		}
+		if last != int64(entries) {
+			t.Errorf("%v: progress reported %d removed entries, want %d", fs.Name(), last, entries)
+		}
+		if _, err := fs.Stat(root); !os.IsNotExist(err) {
+			t.Errorf("%v: tree still exists after RemoveAllWithOptions: %v", fs.Name(), err)
+		}
+
+		if err := cfs.RemoveAllWithOptions(root, nil); err != nil {
+			t.Errorf("%v: RemoveAllWithOptions of missing path = %v, want nil", fs.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+//go:build cgo && !nocephfs
+
+package cephfs
+
+import (
+	"errors"
+	"os"
+	"syscall"
+
+	gocephfs "github.com/ceph/go-ceph/cephfs"
+)
+
+// renameErr wraps an error of renaming oldPath to newPath the way os does.
+// Renames that would cross a quota realm or snapshot boundary fail with
+// syscall.EXDEV, see MoveAcross.
+func renameErr(oldPath, newPath string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if errno, ok := errnoOf(err); ok {
+		err = errno
+	}
+	return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: err}
+}
+
+// RenameNoReplace renames oldPath to newPath, failing with an error that
+// matches fs.ErrExist if newPath exists.
+//
+// libcephfs has no renameat2 with RENAME_NOREPLACE. Regular files are
+// renamed race-free by hard linking them to newPath, which fails if it
+// exists, and unlinking oldPath. Directories and symlinks cannot be linked
+// and are renamed after checking that newPath does not exist, so a newPath
+// created concurrently can still be replaced.
+func (fs *Fs) RenameNoReplace(oldPath, newPath string) (err error) {
+	mount := fs.getMount()
+	defer fs.detectEviction(mount, &err)
+
+	if oldPath, _, err = normPath("rename", oldPath); err != nil {
+		return err
+	}
+	if newPath, _, err = normPath("rename", newPath); err != nil {
+		return err
+	}
+
+	stat, err := mount.Statx(oldPath, gocephfs.StatxMode, gocephfs.AtSymlinkNofollow)
+	if err != nil {
+		return renameErr(oldPath, newPath, err)
+	}
+
+	if !toFileMode(stat.Mode).IsRegular() {
+		if _, err := mount.Statx(newPath, gocephfs.StatxMode, gocephfs.AtSymlinkNofollow); err == nil {
+			return renameErr(oldPath, newPath, syscall.EEXIST)
+		} else if !errors.Is(err, gocephfs.ErrNotExist) {
+			return renameErr(oldPath, newPath, err)
+		}
+		return renameErr(oldPath, newPath, mount.Rename(oldPath, newPath))
+	}
+
+	if err := mount.Link(oldPath, newPath); err != nil {
+		return renameErr(oldPath, newPath, err)
+	}
+	if err := mount.Unlink(oldPath); err != nil {
+		// undo the link so the file is not left under both names
+		mount.Unlink(newPath)
+		return renameErr(oldPath, newPath, err)
+	}
+	return nil
+}
+
+// MoveAcross moves oldPath to newPath like Rename, but falls back to
+// copying and deleting when a rename is not possible because the paths are
+// in different quota realms or snapshot hierarchies. opts configure the
+// copy, see CopyTree, and may be nil.
+//
+// The copy is made next to newPath under a temporary name and renamed into
+// place once complete, so newPath never holds a partial copy. As with
+// Rename, a file at newPath is replaced. oldPath is removed after the copy
+// is in place; if that fails the returned error says so and both copies
+// exist.
+func (fs *Fs) MoveAcross(oldPath, newPath string, opts *CopyOptions) error {
+	return fs.moveAcross(oldPath, newPath, opts, fs.Rename)
+}
+
+// moveAcross is MoveAcross with rename used for both the first attempt and
+// putting the copy in place, so that RenameNoReplace can be passed to never
+// replace newPath.
+func (fs *Fs) moveAcross(oldPath, newPath string, opts *CopyOptions, rename func(oldPath, newPath string) error) error {
+	err := rename(oldPath, newPath)
+	if !IsCrossDevice(err) {
+		return err
+	}
+	// rename succeeded in normalising both paths
+	oldPath, _, _ = normPath("move", oldPath)
+	newPath, _, _ = normPath("move", newPath)
+
+	tmp := atomicTempPath(newPath)
+	if err := fs.CopyTree(oldPath, tmp, opts); err != nil {
+		fs.RemoveAll(tmp)
+		return &os.LinkError{Op: "move", Old: oldPath, New: newPath, Err: err}
+	}
+	if err := rename(tmp, newPath); err != nil {
+		fs.RemoveAll(tmp)
+		return err
+	}
+	if err := fs.RemoveAll(oldPath); err != nil {
+		return &os.LinkError{Op: "move", Old: oldPath, New: newPath, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+//go:build cgo && !nocephfs
+
+package cephfs_test
+
+import (
+	"errors"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"github.com/spf13/afero"
+)
+
+func TestRenameNoReplace(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		tDir := testDir(fs)
+		src := filepath.Join(tDir, "src")
+		dst := filepath.Join(tDir, "dst")
+		srcDir := filepath.Join(tDir, "srcdir")
+		dstDir := filepath.Join(tDir, "dstdir")
+		for _, p := range []string{src, dst} {
+			if err := afero.WriteFile(fs, p, []byte(p), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, p := range []string{srcDir, dstDir} {
+			if err := fs.Mkdir(p, 0o755); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := cfs.RenameNoReplace(src, dst); !errors.Is(err, iofs.ErrExist) {
+			t.Errorf("%v: RenameNoReplace onto file = %v, want %v", fs.Name(), err, iofs.ErrExist)
+		}
+		if err := cfs.RenameNoReplace(srcDir, dstDir); !errors.Is(err, iofs.ErrExist) {
+			t.Errorf("%v: RenameNoReplace onto directory = %v, want %v", fs.Name(), err, iofs.ErrExist)
+		}
+		if got, _ := afero.ReadFile(fs, dst); string(got) != dst {
+			t.Errorf("%v: RenameNoReplace replaced the destination", fs.Name())
+		}
+
+		moved := filepath.Join(tDir, "moved")
+		if err := cfs.RenameNoReplace(src, moved); err != nil {
+			t.Fatalf("%v: RenameNoReplace failed: %v", fs.Name(), err)
+		}
+		if got, _ := afero.ReadFile(fs, moved); string(got) != src {
+			t.Errorf("%v: renamed file has content %q, want %q", fs.Name(), got, src)
+		}
+		if _, err := fs.Stat(src); !os.IsNotExist(err) {
+			t.Errorf("%v: source still exists after RenameNoReplace: %v", fs.Name(), err)
+		}
+
+		var linkErr *os.LinkError
+		if err := fs.Rename(src, moved); !errors.As(err, &linkErr) || !os.IsNotExist(err) {
+			t.Errorf("%v: Rename of missing file = %v, want *os.LinkError for a missing file", fs.Name(), err)
+		}
+
+		if err := cfs.MoveAcross(moved, src, nil); err != nil {
+			t.Fatalf("%v: MoveAcross failed: %v", fs.Name(), err)
+		}
+		if got, _ := afero.ReadFile(fs, src); string(got) != src {
+			t.Errorf("%v: moved file has content %q, want %q", fs.Name(), got, src)
+		}
+	}
+}
+
+func TestMoveAcrossQuota(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		tDir := "/" + testDir(fs)
+		realm := filepath.Join(tDir, "quota")
+		src := filepath.Join(tDir, "src")
+		dst := filepath.Join(realm, "dst")
+		if err := fs.MkdirAll(realm, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := cfs.SetQuota(realm, 1<<30); err != nil {
+			t.Fatalf("%v: SetQuota failed: %v", fs.Name(), err)
+		}
+		if err := afero.WriteFile(fs, filepath.Join(src, "file"), []byte("content"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		// the fallback only runs if the plain rename is refused
+		if err := fs.Rename(src, dst); !cephfs.IsCrossDevice(err) {
+			t.Fatalf("%v: Rename into a quota realm = %v, want a cross device error", fs.Name(), err)
+		}
+
+		if err := cfs.MoveAcross(src, dst, nil); err != nil {
+			t.Fatalf("%v: MoveAcross into a quota realm failed: %v", fs.Name(), err)
+		}
+		if data, err := afero.ReadFile(fs, filepath.Join(dst, "file")); err != nil || string(data) != "content" {
+			t.Errorf("%v: moved file = %q, %v", fs.Name(), data, err)
+		}
+		if _, err := fs.Stat(src); !os.IsNotExist(err) {
+			t.Errorf("%v: source still exists after MoveAcross: %v", fs.Name(), err)
+		}
+		names, err := readDirNames(fs, realm)
+		if err != nil || len(names) != 1 {
+			t.Errorf("%v: %s holds %v, %v, want only the moved directory", fs.Name(), realm, names, err)
+		}
+	}
+}
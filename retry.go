@@ -0,0 +1,296 @@
+package cephfs
+
+import (
+	"errors"
+	"math/rand/v2"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// retryableErrnos are the errnos libcephfs returns for conditions that
+// commonly clear up on their own. ESHUTDOWN is what ceph reports as
+// EBLOCKLISTED.
+var retryableErrnos = map[syscall.Errno]bool{
+	syscall.ETIMEDOUT: true,
+	syscall.EAGAIN:    true,
+	syscall.ESTALE:    true,
+	syscall.EINTR:     true,
+	syscall.ESHUTDOWN: true,
+}
+
+// IsRetryable reports whether err is a transient ceph error that may
+// succeed when the operation is retried.
+func IsRetryable(err error) bool {
+	errno, ok := errnoOf(err)
+	return ok && retryableErrnos[errno]
+}
+
+// RetryPolicy configures how RetryFs retries failed operations.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. Each following
+	// delay is multiplied by Multiplier, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Jitter randomises each delay by up to this fraction of it, so clients
+	// that failed together do not retry in lockstep.
+	Jitter float64
+	// Retryable classifies errors, defaults to IsRetryable.
+	Retryable func(error) bool
+	// OnRetry, if set, is called before every retry.
+	OnRetry func(op, path string, attempt int, err error)
+}
+
+// DefaultRetryPolicy returns the policy used by NewRetryFs for zero fields.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 50 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		Retryable:      IsRetryable,
+	}
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	def := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = def.MaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = def.InitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = def.MaxBackoff
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = def.Multiplier
+	}
+	if p.Jitter < 0 {
+		p.Jitter = 0
+	}
+	if p.Retryable == nil {
+		p.Retryable = def.Retryable
+	}
+	return p
+}
+
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := float64(p.InitialBackoff)
+	for i := 1; i < retry; i++ {
+		d *= p.Multiplier
+	}
+	d = min(d, float64(p.MaxBackoff))
+	d += d * p.Jitter * (2*rand.Float64() - 1)
+	return time.Duration(d)
+}
+
+// RetryStats counts the retries performed by a RetryFs.
+type RetryStats struct {
+	// Retries is the number of retried attempts.
+	Retries uint64
+	// Recovered is the number of operations that succeeded after a retry.
+	Recovered uint64
+	// Exhausted is the number of operations that still failed with a
+	// retryable error after MaxAttempts.
+	Exhausted uint64
+}
+
+// RetryFs wraps an afero.Fs and retries its idempotent operations when they
+// fail with a retryable error: Stat, read-only Open, Mkdir, and ReadAt and
+// Readdir on files opened through it. Other operations are passed through
+// unchanged.
+type RetryFs struct {
+	base   afero.Fs
+	policy RetryPolicy
+
+	retries   atomic.Uint64
+	recovered atomic.Uint64
+	exhausted atomic.Uint64
+}
+
+// NewRetryFs wraps base with the given policy. Zero fields of policy are
+// filled in from DefaultRetryPolicy.
+func NewRetryFs(base afero.Fs, policy RetryPolicy) *RetryFs {
+	return &RetryFs{base: base, policy: policy.withDefaults()}
+}
+
+// Stats returns a snapshot of the retry counters.
+func (rfs *RetryFs) Stats() RetryStats {
+	return RetryStats{
+		Retries:   rfs.retries.Load(),
+		Recovered: rfs.recovered.Load(),
+		Exhausted: rfs.exhausted.Load(),
+	}
+}
+
+// do runs op until it succeeds, fails with an error that is not retryable
+// or runs out of attempts.
+func (rfs *RetryFs) do(name, path string, op func() error) error {
+	err := op()
+	for attempt := 1; err != nil && rfs.policy.Retryable(err); attempt++ {
+		if attempt >= rfs.policy.MaxAttempts {
+			rfs.exhausted.Add(1)
+			return err
+		}
+		if rfs.policy.OnRetry != nil {
+			rfs.policy.OnRetry(name, path, attempt, err)
+		}
+		time.Sleep(rfs.policy.backoff(attempt))
+		rfs.retries.Add(1)
+
+		if err = op(); err == nil {
+			rfs.recovered.Add(1)
+		}
+	}
+	return err
+}
+
+func (rfs *RetryFs) Create(path string) (afero.File, error) {
+	return rfs.base.Create(path)
+}
+
+// Mkdir retries a failed Mkdir. If a retried attempt reports that the
+// directory exists, an earlier attempt created it before failing and the
+// call succeeds.
+func (rfs *RetryFs) Mkdir(path string, perm os.FileMode) error {
+	retried := false
+	err := rfs.do("mkdir", path, func() error {
+		err := rfs.base.Mkdir(path, perm)
+		if retried && errors.Is(err, os.ErrExist) {
+			if info, statErr := rfs.base.Stat(path); statErr == nil && info.IsDir() {
+				return nil
+			}
+		}
+		retried = true
+		return err
+	})
+	return err
+}
+
+func (rfs *RetryFs) MkdirAll(path string, perm os.FileMode) error {
+	return rfs.base.MkdirAll(path, perm)
+}
+
+func (rfs *RetryFs) Open(path string) (afero.File, error) {
+	return rfs.OpenFile(path, os.O_RDONLY, 0)
+}
+
+// OpenFile retries read-only opens. Opens that may create, truncate or
+// append are not idempotent and are attempted once.
+func (rfs *RetryFs) OpenFile(path string, flag int, perm os.FileMode) (afero.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return rfs.base.OpenFile(path, flag, perm)
+	}
+
+	var f afero.File
+	err := rfs.do("open", path, func() error {
+		var err error
+		f, err = rfs.base.OpenFile(path, flag, perm)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &retryFile{f, rfs}, nil
+}
+
+func (rfs *RetryFs) Remove(path string) error {
+	return rfs.base.Remove(path)
+}
+
+func (rfs *RetryFs) RemoveAll(path string) error {
+	return rfs.base.RemoveAll(path)
+}
+
+func (rfs *RetryFs) Rename(oldPath, newPath string) error {
+	return rfs.base.Rename(oldPath, newPath)
+}
+
+func (rfs *RetryFs) Stat(path string) (os.FileInfo, error) {
+	var info os.FileInfo
+	err := rfs.do("stat", path, func() error {
+		var err error
+		info, err = rfs.base.Stat(path)
+		return err
+	})
+	return info, err
+}
+
+func (rfs *RetryFs) Name() string {
+	return rfs.base.Name()
+}
+
+func (rfs *RetryFs) Chmod(path string, mode os.FileMode) error {
+	return rfs.base.Chmod(path, mode)
+}
+
+func (rfs *RetryFs) Chown(path string, uid int, gid int) error {
+	return rfs.base.Chown(path, uid, gid)
+}
+
+func (rfs *RetryFs) Chtimes(path string, atime time.Time, mtime time.Time) error {
+	return rfs.base.Chtimes(path, atime, mtime)
+}
+
+// retryFile retries ReadAt, Readdir and Readdirnames of a file opened for
+// reading.
+type retryFile struct {
+	afero.File
+	rfs *RetryFs
+}
+
+func (f *retryFile) ReadAt(buf []byte, offset int64) (int, error) {
+	total := 0
+	err := f.rfs.do("readat", f.Name(), func() error {
+		n, err := f.File.ReadAt(buf[total:], offset+int64(total))
+		total += n
+		return err
+	})
+	return total, err
+}
+
+// Readdir retries from the position the failed call reached, so entries
+// returned before the failure are kept and not listed twice.
+func (f *retryFile) Readdir(count int) ([]os.FileInfo, error) {
+	list := make([]os.FileInfo, 0)
+	err := f.rfs.do("readdir", f.Name(), func() error {
+		n := count
+		if count > 0 {
+			n = count - len(list)
+			if n <= 0 {
+				return nil
+			}
+		}
+		items, err := f.File.Readdir(n)
+		list = append(list, items...)
+		return err
+	})
+	return list, err
+}
+
+// Readdirnames retries like Readdir, without the stat of every entry
+// that listing FileInfos costs.
+func (f *retryFile) Readdirnames(count int) ([]string, error) {
+	list := make([]string, 0)
+	err := f.rfs.do("readdirnames", f.Name(), func() error {
+		n := count
+		if count > 0 {
+			n = count - len(list)
+			if n <= 0 {
+				return nil
+			}
+		}
+		names, err := f.File.Readdirnames(n)
+		list = append(list, names...)
+		return err
+	})
+	return list, err
+}
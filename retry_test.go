@@ -0,0 +1,144 @@
+package cephfs_test
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"github.com/spf13/afero"
+)
+
+var fastRetries = cephfs.RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Microsecond,
+	MaxBackoff:     time.Millisecond,
+}
+
+func TestRetryFsRecovers(t *testing.T) {
+	base := afero.NewMemMapFs()
+	afero.WriteFile(base, "/file", []byte("data"), 0o644)
+
+	faulty := cephfs.NewFaultyFs(base, 1, cephfs.Fault{Err: syscall.ETIMEDOUT, Times: 2})
+	fs := cephfs.NewRetryFs(faulty, fastRetries)
+	if _, err := fs.Stat("/file"); err != nil {
+		t.Fatalf("Stat failed after retries: %v", err)
+	}
+
+	stats := fs.Stats()
+	if stats.Retries != 2 || stats.Recovered != 1 || stats.Exhausted != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestRetryFsExhausted(t *testing.T) {
+	faulty := cephfs.NewFaultyFs(afero.NewMemMapFs(), 1, cephfs.Fault{Err: syscall.ESTALE, Times: 5})
+	fs := cephfs.NewRetryFs(faulty, fastRetries)
+	if _, err := fs.Stat("/file"); !errors.Is(err, syscall.ESTALE) {
+		t.Fatalf("Stat = %v, want %v", err, syscall.ESTALE)
+	}
+	if stats := fs.Stats(); stats.Retries != 2 || stats.Exhausted != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestRetryFsNotRetryable(t *testing.T) {
+	faulty := cephfs.NewFaultyFs(afero.NewMemMapFs(), 1, cephfs.Fault{Err: syscall.EACCES, Times: 1})
+	fs := cephfs.NewRetryFs(faulty, fastRetries)
+	if _, err := fs.Stat("/file"); !errors.Is(err, syscall.EACCES) {
+		t.Fatalf("Stat = %v, want %v", err, syscall.EACCES)
+	}
+	if stats := fs.Stats(); stats.Retries != 0 {
+		t.Errorf("non retryable error was retried: %+v", stats)
+	}
+}
+
+func TestRetryFsMkdirExists(t *testing.T) {
+	// the directory exists because the attempt that timed out created it,
+	// like a reply lost to a timeout
+	base := afero.NewMemMapFs()
+	base.Mkdir("/dir", 0o755)
+
+	faulty := cephfs.NewFaultyFs(base, 1, cephfs.Fault{Ops: []string{"mkdir"}, Err: syscall.ETIMEDOUT, Times: 1})
+	fs := cephfs.NewRetryFs(faulty, fastRetries)
+	if err := fs.Mkdir("/dir", 0o755); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+	if err := fs.Mkdir("/dir", 0o755); !os.IsExist(err) {
+		t.Errorf("Mkdir of existing dir = %v, want exists error", err)
+	}
+}
+
+func TestRetryFsReadAt(t *testing.T) {
+	base := afero.NewMemMapFs()
+	afero.WriteFile(base, "/file", []byte("0123456789"), 0o644)
+
+	faulty := cephfs.NewFaultyFs(base, 1)
+	fs := cephfs.NewRetryFs(faulty, fastRetries)
+	f, err := fs.Open("/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	faulty.SetFaults(cephfs.Fault{Ops: []string{"read"}, Partial: true, Err: syscall.ETIMEDOUT, Times: 1})
+	buf := make([]byte, 6)
+	n, err := f.ReadAt(buf, 2)
+	if err != nil {
+		t.Fatalf("ReadAt failed after retries: %v", err)
+	}
+	if got := string(buf[:n]); got != "234567" {
+		t.Errorf("ReadAt = %q, want %q", got, "234567")
+	}
+	if stats := fs.Stats(); stats.Retries != 1 || stats.Recovered != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestRetryFsReaddir(t *testing.T) {
+	base := afero.NewMemMapFs()
+	want := []string{"a", "b", "c", "d"}
+	for _, name := range want {
+		afero.WriteFile(base, "/dir/"+name, nil, 0o644)
+	}
+
+	faulty := cephfs.NewFaultyFs(base, 1)
+	fs := cephfs.NewRetryFs(faulty, fastRetries)
+	for _, tc := range []struct {
+		name    string
+		readdir func(f afero.File) ([]string, error)
+	}{
+		{"Readdir", func(f afero.File) ([]string, error) {
+			infos, err := f.Readdir(-1)
+			var names []string
+			for _, info := range infos {
+				names = append(names, info.Name())
+			}
+			return names, err
+		}},
+		{"Readdirnames", func(f afero.File) ([]string, error) {
+			return f.Readdirnames(-1)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := fs.Open("/dir")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			faulty.SetFaults(cephfs.Fault{Ops: []string{"readdir"}, Partial: true, Err: syscall.ETIMEDOUT, Times: 2})
+			names, err := tc.readdir(f)
+			if err != nil {
+				t.Fatalf("%s failed after retries: %v", tc.name, err)
+			}
+			slices.Sort(names)
+			if !slices.Equal(names, want) {
+				t.Errorf("%s = %v, want %v", tc.name, names, want)
+			}
+		})
+	}
+}
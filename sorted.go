@@ -0,0 +1,262 @@
+//go:build cgo && !nocephfs
+
+package cephfs
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+
+	gocephfs "github.com/ceph/go-ceph/cephfs"
+)
+
+// WithSortedReaddir makes Readdir and Readdirnames return entries in
+// lexical order of their names instead of the order of the directory
+// stream, which cephfs does not keep stable.
+//
+// The names are sorted on the first Readdir call after opening or
+// rewinding the directory, later calls page through the sorted list.
+// Directories with more than memEntries entries are sorted in runs of that
+// size that are spilled to temporary files in os.TempDir and merged, so
+// memory stays bounded for huge directories. A memEntries of 0 or less
+// uses DefaultSortMemEntries.
+//
+// Only names are kept while sorting, as keeping the stats from the
+// directory stream would break the memory bound. Readdir instead stats
+// each entry with its own statx when it is returned, which costs a round
+// trip to the MDS per entry where unsorted Readdir needs none; use
+// Readdirnames when the stats are not needed. Entries removed while the
+// directory is listed are left out by Readdir, Readdirnames does not stat
+// them and may return them. Entries created meanwhile are not listed.
+func WithSortedReaddir(memEntries int) Option {
+	return func(fs *Fs) {
+		if memEntries <= 0 {
+			memEntries = DefaultSortMemEntries
+		}
+		fs.sortMemEntries = memEntries
+	}
+}
+
+// sortedDir yields the names of a directory in lexical order.
+type sortedDir struct {
+	// names is the sorted directory if it fit in memory
+	names []string
+	// runs are the spilled runs if it did not
+	runs  []*sortRun
+	merge runHeap
+}
+
+// sortRun is a sorted run of names in a temporary file, each stored as a
+// uvarint length followed by the name.
+type sortRun struct {
+	file *os.File
+	r    *bufio.Reader
+	head string
+}
+
+func newSortedDir(ctx context.Context, dir *gocephfs.Directory, memEntries int) (_ *sortedDir, err error) {
+	s := &sortedDir{}
+	defer func() {
+		if err != nil {
+			s.close()
+		}
+	}()
+
+	buf := make([]string, 0, min(memEntries, 1024))
+	err = forDirItem(dir, func(de *gocephfs.DirEntry) error {
+		if name := de.Name(); name == "." || name == ".." {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if len(buf) == memEntries {
+			if err := s.spill(buf); err != nil {
+				return err
+			}
+			buf = buf[:0]
+		}
+		buf = append(buf, de.Name())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.runs == nil {
+		sort.Strings(buf)
+		s.names = buf
+		return s, nil
+	}
+
+	if len(buf) > 0 {
+		if err := s.spill(buf); err != nil {
+			return nil, err
+		}
+	}
+	for _, run := range s.runs {
+		if _, err := run.file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		run.r = bufio.NewReader(run.file)
+		if err := run.advance(); err != nil {
+			return nil, err
+		}
+		s.merge = append(s.merge, run)
+	}
+	heap.Init(&s.merge)
+	return s, nil
+}
+
+// spill writes names as a sorted run to a temporary file.
+func (s *sortedDir) spill(names []string) error {
+	sort.Strings(names)
+
+	file, err := os.CreateTemp("", "cephfs-readdir-*")
+	if err != nil {
+		return err
+	}
+	// the run is only reachable through the open file
+	os.Remove(file.Name())
+	s.runs = append(s.runs, &sortRun{file: file})
+
+	w := bufio.NewWriter(file)
+	var lenBuf [binary.MaxVarintLen64]byte
+	for _, name := range names {
+		n := binary.PutUvarint(lenBuf[:], uint64(len(name)))
+		if _, err := w.Write(lenBuf[:n]); err != nil {
+			return err
+		}
+		if _, err := w.WriteString(name); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// next returns the next name, or io.EOF after the last one.
+func (s *sortedDir) next() (string, error) {
+	if s.runs == nil {
+		if len(s.names) == 0 {
+			return "", io.EOF
+		}
+		name := s.names[0]
+		s.names = s.names[1:]
+		return name, nil
+	}
+
+	if len(s.merge) == 0 {
+		return "", io.EOF
+	}
+	run := s.merge[0]
+	name := run.head
+	if err := run.advance(); err != nil {
+		if err != io.EOF {
+			return "", err
+		}
+		heap.Pop(&s.merge)
+	} else {
+		heap.Fix(&s.merge, 0)
+	}
+	return name, nil
+}
+
+func (s *sortedDir) close() error {
+	var errs []error
+	for _, run := range s.runs {
+		if err := run.file.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.runs = nil
+	s.names = nil
+	s.merge = nil
+	return errors.Join(errs...)
+}
+
+// advance reads the next name of the run into head.
+func (r *sortRun) advance() error {
+	n, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return err
+	}
+	name := make([]byte, n)
+	if _, err := io.ReadFull(r.r, name); err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	r.head = string(name)
+	return nil
+}
+
+// runHeap orders runs by their next name.
+type runHeap []*sortRun
+
+func (h runHeap) Len() int           { return len(h) }
+func (h runHeap) Less(i, j int) bool { return h[i].head < h[j].head }
+func (h runHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x any)        { *h = append(*h, x.(*sortRun)) }
+func (h *runHeap) Pop() any {
+	old := *h
+	run := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return run
+}
+
+// readSorted is readEntries for Fs created WithSortedReaddir. With withStat
+// every entry is statted separately, see WithSortedReaddir.
+func (f *File) readSorted(ctx context.Context, count int, withStat bool) ([]dirent, error) {
+	if f.sorted == nil {
+		sorted, err := newSortedDir(ctx, f.dir, f.fs.sortMemEntries)
+		if err != nil {
+			return nil, f.wrapErr("readdirent", err)
+		}
+		f.sorted = sorted
+	}
+
+	if count == 0 {
+		count = -1
+	}
+
+	list := make([]dirent, 0)
+	for count != 0 {
+		if err := ctx.Err(); err != nil {
+			return list, err
+		}
+		name, err := f.sorted.next()
+		if err == io.EOF {
+			if count > 0 {
+				return list, io.EOF
+			}
+			return list, nil
+		}
+		if err != nil {
+			return list, f.wrapErr("readdirent", err)
+		}
+
+		de := dirent{name: name, dtype: gocephfs.DTypeUnknown}
+		if withStat {
+			stat, err := f.mount.Statx(filepath.Join(f.path, name), f.fs.readdirMask, gocephfs.AtSymlinkNofollow)
+			if err != nil {
+				if errno, ok := errnoOf(err); ok && errno == syscall.ENOENT {
+					// removed since the directory was read
+					continue
+				}
+				return list, f.wrapErr("readdirent", err)
+			}
+			de.stat = stat
+		}
+		list = append(list, de)
+
+		if count > 0 {
+			count--
+		}
+	}
+	return list, nil
+}
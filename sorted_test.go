@@ -0,0 +1,77 @@
+//go:build cgo && !nocephfs
+
+package cephfs_test
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"github.com/spf13/afero"
+)
+
+func TestSortedReaddir(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		if _, ok := fs.(*cephfs.Fs); !ok {
+			continue
+		}
+
+		// a small limit makes the sort spill to disk
+		sortedFs, err := cephfs.NewCephFS(cephfs.WithSortedReaddir(3))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sortedFs.Unmount()
+
+		tDir := testDir(fs)
+		var want []string
+		for _, i := range []int{7, 2, 9, 0, 4, 1, 8, 3, 6, 5} {
+			name := fmt.Sprintf("file%d", i)
+			if err := afero.WriteFile(fs, filepath.Join(tDir, name), nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			want = append(want, name)
+		}
+		sort.Strings(want)
+
+		dir, err := sortedFs.Open(tDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer dir.Close()
+
+		var got []string
+		for {
+			names, err := dir.Readdirnames(4)
+			got = append(got, names...)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%v: Readdirnames failed: %v", fs.Name(), err)
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%v: sorted Readdirnames = %v, want %v", fs.Name(), got, want)
+		}
+
+		if _, err := dir.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		infos, err := dir.Readdir(-1)
+		if err != nil {
+			t.Fatalf("%v: Readdir after rewind failed: %v", fs.Name(), err)
+		}
+		got = got[:0]
+		for _, info := range infos {
+			got = append(got, info.Name())
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%v: sorted Readdir after rewind = %v, want %v", fs.Name(), got, want)
+		}
+	}
+}
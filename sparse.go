@@ -0,0 +1,72 @@
+//go:build cgo && !nocephfs
+
+package cephfs
+
+import (
+	"io"
+	"os"
+	"syscall"
+
+	gocephfs "github.com/ceph/go-ceph/cephfs"
+)
+
+// Allocate preallocates space for the byte range [off, off+length). The
+// file size grows if the range extends past the end of the file.
+//
+// libcephfs rejects a plain fallocate with EOPNOTSUPP, see
+// https://tracker.ceph.com/issues/68026, so the range is allocated with
+// FALLOC_FL_KEEP_SIZE and the file is then extended with a truncate. CephFS
+// does not reserve space on the OSDs either way, so a later write can
+// still fail with ENOSPC or EDQUOT.
+func (f *File) Allocate(off, length int64) (err error) {
+	if err := f.checkData("fallocate", syscall.EISDIR); err != nil {
+		return err
+	}
+	defer f.detectEviction(&err)
+	if err := f.file.Fallocate(gocephfs.FallocFlKeepSize, off, length); err != nil {
+		return f.wrapErr("fallocate", err)
+	}
+
+	stat, err := f.file.Fstatx(gocephfs.StatxSize, 0)
+	if err != nil {
+		return f.wrapErr("fallocate", err)
+	}
+	if end := off + length; end > int64(stat.Size) {
+		return f.wrapErr("fallocate", f.file.Truncate(end))
+	}
+	return nil
+}
+
+// PunchHole deallocates the byte range [off, off+length), which reads back
+// as zeros afterwards. The file size is not changed.
+func (f *File) PunchHole(off, length int64) (err error) {
+	if err := f.checkData("fallocate", syscall.EISDIR); err != nil {
+		return err
+	}
+	defer f.detectEviction(&err)
+	mode := gocephfs.FallocFlPunchHole | gocephfs.FallocFlKeepSize
+	return f.wrapErr("fallocate", f.file.Fallocate(mode, off, length))
+}
+
+// seekSparse implements SeekData and SeekHole. go-ceph only passes the
+// standard whence values through to libcephfs, so holes cannot be located
+// and the file is reported as data up to its end, followed by the implicit
+// hole at the end of file. lseek(2) allows this for filesystems without
+// hole reporting.
+func (f *File) seekSparse(offset int64, whence int) (int64, error) {
+	stat, err := f.file.Fstatx(gocephfs.StatxSize, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	size := int64(stat.Size)
+	if offset < 0 || offset >= size {
+		return 0, &os.PathError{Op: "seek", Path: f.path, Err: syscall.ENXIO}
+	}
+
+	pos := offset
+	if whence == SeekHole {
+		pos = size
+	}
+	return f.file.Seek(pos, io.SeekStart)
+}
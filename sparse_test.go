@@ -0,0 +1,98 @@
+//go:build cgo && !nocephfs
+
+package cephfs_test
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"testing"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"github.com/spf13/afero"
+)
+
+func TestAllocateAndPunchHole(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		f := tmpFile(fs)
+		defer f.Close()
+
+		cf, ok := f.(*cephfs.File)
+		if !ok {
+			continue
+		}
+
+		if err := cf.Allocate(0, 8192); err != nil {
+			t.Fatalf("%v: Allocate failed: %v", fs.Name(), err)
+		}
+		checkSize(t, f, 8192)
+		if err := cf.Allocate(0, 4096); err != nil {
+			t.Fatalf("%v: Allocate within the file failed: %v", fs.Name(), err)
+		}
+		checkSize(t, f, 8192)
+		if got, err := afero.ReadFile(fs, f.Name()); err != nil || !bytes.Equal(got, make([]byte, 8192)) {
+			t.Errorf("%v: allocated range does not read back as zeros: %v", fs.Name(), err)
+		}
+
+		data := bytes.Repeat([]byte{'x'}, 8192)
+		if _, err := f.WriteAt(data, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := cf.PunchHole(1024, 2048); err != nil {
+			t.Fatalf("%v: PunchHole failed: %v", fs.Name(), err)
+		}
+		checkSize(t, f, 8192)
+
+		got := make([]byte, 2048)
+		if _, err := f.ReadAt(got, 1024); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, make([]byte, 2048)) {
+			t.Errorf("%v: punched range does not read back as zeros", fs.Name())
+		}
+
+		if off, err := f.Seek(0, cephfs.SeekData); off != 0 || err != nil {
+			t.Errorf("%v: Seek(0, SeekData) = %d, %v, want 0, nil", fs.Name(), off, err)
+		}
+		if off, err := f.Seek(0, cephfs.SeekHole); off != 8192 || err != nil {
+			t.Errorf("%v: Seek(0, SeekHole) = %d, %v, want 8192, nil", fs.Name(), off, err)
+		}
+		if _, err := f.Seek(8192, cephfs.SeekData); err == nil {
+			t.Errorf("%v: Seek(SeekData) at end of file should fail", fs.Name())
+		}
+		f.Seek(0, io.SeekStart)
+	}
+}
+
+func TestCopyFileSparse(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		tDir := testDir(fs)
+		src := filepath.Join(tDir, "src")
+		dst := filepath.Join(tDir, "dst")
+
+		data := make([]byte, 4*4096)
+		copy(data[4096:], "data between holes")
+		if err := afero.WriteFile(fs, src, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := cfs.CopyFile(src, dst, &cephfs.CopyOptions{ChunkSize: 4096, Sparse: true}); err != nil {
+			t.Fatalf("%v: sparse CopyFile failed: %v", fs.Name(), err)
+		}
+
+		got, err := afero.ReadFile(fs, dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("%v: sparse copy differs from source", fs.Name())
+		}
+	}
+}
@@ -0,0 +1,96 @@
+//go:build cgo && !nocephfs
+
+package cephfs
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	gocephfs "github.com/ceph/go-ceph/cephfs"
+)
+
+// Statfs returns the capacity of the filesystem combined with the quota
+// that applies to path, if any.
+func (fs *Fs) Statfs(path string) (_ *DiskUsage, err error) {
+	mount := fs.getMount()
+	defer fs.detectEviction(mount, &err)
+
+	if path, _, err = normPath("statfs", path); err != nil {
+		return nil, err
+	}
+	vfs, err := mount.StatFS(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to statfs %s: %w", path, convertErr(err))
+	}
+
+	frsize := uint64(vfs.Frsize)
+	st := &DiskUsage{
+		TotalBytes:            vfs.Blocks * frsize,
+		FreeBytes:             vfs.Bfree * frsize,
+		ClusterAvailableBytes: vfs.Bavail * frsize,
+		TotalFiles:            vfs.Files,
+		FreeFiles:             vfs.Ffree,
+	}
+	st.AvailableBytes = st.ClusterAvailableBytes
+	st.AvailableFiles = st.FreeFiles
+
+	// quotas of the parents of a relative path are found by walking up from
+	// its absolute form
+	root, maxBytes, maxFiles := findQuota(mount, absPath(mount, path))
+	if root == "" {
+		return st, nil
+	}
+
+	st.QuotaRoot = root
+	st.QuotaMaxBytes = maxBytes
+	st.QuotaMaxFiles = maxFiles
+	st.QuotaUsedBytes, _ = getUintXattr(mount, root, "ceph.dir.rbytes")
+	st.QuotaUsedFiles, _ = getUintXattr(mount, root, "ceph.dir.rfiles")
+
+	if maxBytes > 0 {
+		st.AvailableBytes = min(st.AvailableBytes, remaining(maxBytes, st.QuotaUsedBytes))
+	}
+	if maxFiles > 0 {
+		st.AvailableFiles = min(st.AvailableFiles, remaining(maxFiles, st.QuotaUsedFiles))
+	}
+
+	return st, nil
+}
+
+func remaining(limit, used uint64) uint64 {
+	if used >= limit {
+		return 0
+	}
+	return limit - used
+}
+
+// findQuota walks up from path to the nearest directory with a byte or file
+// quota set.
+func findQuota(mount *gocephfs.MountInfo, path string) (root string, maxBytes, maxFiles uint64) {
+	for dir := path; ; dir = filepath.Dir(dir) {
+		maxBytes, _ = getUintXattr(mount, dir, "ceph.quota.max_bytes")
+		maxFiles, _ = getUintXattr(mount, dir, "ceph.quota.max_files")
+		if maxBytes > 0 || maxFiles > 0 {
+			return dir, maxBytes, maxFiles
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return "", 0, 0
+		}
+	}
+}
+
+func getUintXattr(mount *gocephfs.MountInfo, path, name string) (uint64, error) {
+	value, err := mount.GetXattr(path, name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(trimXattr(value), 10, 64)
+}
+
+// trimXattr returns a vxattr value as a string without the terminating NUL
+// or whitespace ceph may include.
+func trimXattr(value []byte) string {
+	return strings.TrimSpace(strings.TrimRight(string(value), "\x00"))
+}
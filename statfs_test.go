@@ -0,0 +1,67 @@
+//go:build cgo && !nocephfs
+
+package cephfs_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"github.com/spf13/afero"
+)
+
+func TestStatfs(t *testing.T) {
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		usage, err := cfs.Statfs(testingDirPath)
+		if err != nil {
+			t.Fatalf("%v: Statfs failed: %v", fs.Name(), err)
+		}
+		if usage.TotalBytes == 0 {
+			t.Errorf("%v: Statfs reported no capacity: %+v", fs.Name(), usage)
+		}
+		if usage.AvailableBytes > usage.ClusterAvailableBytes {
+			t.Errorf("%v: available %d exceeds cluster available %d", fs.Name(), usage.AvailableBytes, usage.ClusterAvailableBytes)
+		}
+	}
+}
+
+func TestStatfsQuota(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		const quota = 1 << 20
+		tDir := "/" + testDir(fs)
+		nested := filepath.Join(tDir, "a", "b")
+		if err := fs.MkdirAll(nested, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := cfs.SetQuota(tDir, quota); err != nil {
+			t.Fatalf("%v: SetQuota failed: %v", fs.Name(), err)
+		}
+		if err := afero.WriteFile(fs, filepath.Join(nested, testName), make([]byte, 4096), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		usage, err := cfs.Statfs(nested)
+		if err != nil {
+			t.Fatalf("%v: Statfs failed: %v", fs.Name(), err)
+		}
+		if usage.QuotaRoot != tDir || usage.QuotaMaxBytes != quota {
+			t.Errorf("%v: quota of %s = %q, %d, want %q, %d", fs.Name(), nested, usage.QuotaRoot, usage.QuotaMaxBytes, tDir, quota)
+		}
+		// the recursive usage is updated lazily, so it is not checked
+		// against the file just written
+		if want := min(usage.ClusterAvailableBytes, quota-min(usage.QuotaUsedBytes, quota)); usage.AvailableBytes != want {
+			t.Errorf("%v: available %d, want %d capped by the quota: %+v", fs.Name(), usage.AvailableBytes, want, usage)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+//go:build cgo && !nocephfs
+
+package cephfs
+
+import (
+	"os"
+	"syscall"
+
+	gocephfs "github.com/ceph/go-ceph/cephfs"
+)
+
+// syncFlags are the open flags that request synchronous writes. os.O_SYNC
+// includes the O_DSYNC bit.
+const syncFlags = os.O_SYNC | syscall.O_DSYNC
+
+// WithSyncOnClose makes Close fsync files that were opened for writing
+// before closing them, so a successful Close means the data is durable.
+func WithSyncOnClose() Option {
+	return func(fs *Fs) {
+		fs.syncOnClose = true
+	}
+}
+
+// Datasync flushes the file's data, and only the metadata needed to read
+// it back such as its size, to stable storage. It is cheaper than Sync,
+// which also flushes metadata like timestamps.
+func (f *File) Datasync() (err error) {
+	if err := f.checkFile("sync"); err != nil {
+		return err
+	}
+	defer f.detectEviction(&err)
+	return f.wrapErr("sync", f.file.Fsync(gocephfs.SyncDataOnly))
+}
+
+func (f *File) writable() bool {
+	return f.flag&(os.O_WRONLY|os.O_RDWR) != 0
+}
+
+// syncWrite gives writes to files opened with O_SYNC or O_DSYNC their
+// synchronous semantics by flushing after every write.
+func (f *File) syncWrite() error {
+	switch {
+	case f.flag&os.O_SYNC == os.O_SYNC:
+		return f.file.Fsync(gocephfs.SyncAll)
+	case f.flag&syscall.O_DSYNC != 0:
+		return f.file.Fsync(gocephfs.SyncDataOnly)
+	}
+	return nil
+}
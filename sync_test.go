@@ -0,0 +1,45 @@
+//go:build cgo && !nocephfs
+
+package cephfs_test
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"github.com/spf13/afero"
+)
+
+func TestOpenFileSync(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		tDir := testDir(fs)
+		path := filepath.Join(tDir, testName)
+
+		for _, flag := range []int{0, os.O_SYNC, syscall.O_DSYNC} {
+			f, err := fs.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC|flag, 0o644)
+			if err != nil {
+				t.Fatalf("%v: OpenFile(%#x) failed: %v", fs.Name(), flag, err)
+			}
+			if _, err := f.WriteString("durable"); err != nil {
+				t.Errorf("%v: write with flag %#x failed: %v", fs.Name(), flag, err)
+			}
+			if cf, ok := f.(*cephfs.File); ok {
+				if err := cf.Datasync(); err != nil {
+					t.Errorf("%v: Datasync failed: %v", fs.Name(), err)
+				}
+			}
+			f.Close()
+
+			got, err := afero.ReadFile(fs, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != "durable" {
+				t.Errorf("%v: content = %q, want %q", fs.Name(), got, "durable")
+			}
+		}
+	}
+}
@@ -0,0 +1,32 @@
+//go:build cgo && !nocephfs
+
+package cephfs
+
+import "context"
+
+// WithTracer traces the Stat, OpenFile, Read, Write, Readdir, RemoveAll and
+// Rename operations of the Fs and its files with tracer. Create and Open
+// are traced as OpenFile, ReadAt and WriteAt as Read and Write, and
+// Readdirnames as Readdir.
+//
+// Operations on the Fs start root spans. Operations through a ContextFs
+// start their spans as children of the span in its context, so they show up
+// in the trace of the request they are made for.
+func WithTracer(tracer Tracer) Option {
+	return func(fs *Fs) {
+		fs.tracer = tracer
+	}
+}
+
+func (fs *Fs) startSpan(ctx context.Context, op string, attrs ...Attribute) (context.Context, *span) {
+	return startSpan(fs.tracer, ctx, op, attrs...)
+}
+
+// traceIO runs a read or write in a span recording the bytes it
+// transferred.
+func (f *File) traceIO(ctx context.Context, op string, io func() (int, error)) (int, error) {
+	_, span := f.fs.startSpan(ctx, op, Attribute{AttrPath, f.path})
+	n, err := io()
+	span.end(err, Attribute{AttrBytes, int64(n)})
+	return n, err
+}
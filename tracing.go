@@ -0,0 +1,77 @@
+package cephfs
+
+import "context"
+
+// Attribute keys of the spans started for traced operations.
+const (
+	AttrPath    = "cephfs.path"
+	AttrNewPath = "cephfs.new_path"
+	AttrFlags   = "cephfs.flags"
+	AttrBytes   = "cephfs.bytes"
+	AttrCount   = "cephfs.count"
+	AttrEntries = "cephfs.entries"
+	AttrErrno   = "cephfs.errno"
+)
+
+// Attribute is a key and value describing a traced operation. Values are
+// strings or int64s.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Tracer starts a span for every traced operation of an Fs, see
+// WithTracer. Implementations adapt it to a tracing library, the
+// otelcephfs package does so for OpenTelemetry.
+type Tracer interface {
+	// Start starts a span for op as a child of the span in ctx, if any,
+	// and returns a context holding the new span.
+	Start(ctx context.Context, op string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is an operation being traced.
+type Span interface {
+	// SetAttributes adds attributes known once the operation is done, such
+	// as the number of bytes it transferred.
+	SetAttributes(attrs ...Attribute)
+	// End ends the span. err is the error the operation failed with, nil
+	// if it succeeded. Reaching the end of a file is a success.
+	End(err error)
+}
+
+// span is a started Span, or nil when tracing is disabled.
+type span struct {
+	span Span
+}
+
+// startSpan starts a span for op with tracer, if any, and returns the
+// context holding it, for the operations op is made of to start their
+// spans in.
+func startSpan(tracer Tracer, ctx context.Context, op string, attrs ...Attribute) (context.Context, *span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if tracer == nil {
+		return ctx, nil
+	}
+	ctx, s := tracer.Start(ctx, op, attrs...)
+	return ctx, &span{s}
+}
+
+// end ends s with the outcome of the operation, adding attrs and the errno
+// of err.
+func (s *span) end(err error, attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	errno := errnoName(err)
+	if errno == "" {
+		err = nil
+	} else {
+		attrs = append(attrs, Attribute{AttrErrno, errno})
+	}
+	if len(attrs) > 0 {
+		s.span.SetAttributes(attrs...)
+	}
+	s.span.End(err)
+}
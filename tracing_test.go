@@ -0,0 +1,123 @@
+//go:build cgo && !nocephfs
+
+package cephfs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	cephfs "github.com/crimsonfez/afero-cephfs"
+)
+
+type parentKey struct{}
+
+type recordedSpan struct {
+	op     string
+	parent any
+	attrs  map[string]any
+	err    error
+}
+
+// recordingTracer records the spans of an Fs. The parent of a span is the
+// value stored under parentKey in the context it was started with.
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (rt *recordingTracer) Start(ctx context.Context, op string, attrs ...cephfs.Attribute) (context.Context, cephfs.Span) {
+	s := &recordedSpan{op: op, parent: ctx.Value(parentKey{}), attrs: make(map[string]any)}
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+	return ctx, &recordingSpan{s, rt}
+}
+
+func (rt *recordingTracer) find(op string) *recordedSpan {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	for _, s := range rt.spans {
+		if s.op == op {
+			return s
+		}
+	}
+	return nil
+}
+
+type recordingSpan struct {
+	*recordedSpan
+	rt *recordingTracer
+}
+
+func (s *recordingSpan) SetAttributes(attrs ...cephfs.Attribute) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordingSpan) End(err error) {
+	s.err = err
+	s.rt.mu.Lock()
+	defer s.rt.mu.Unlock()
+	s.rt.spans = append(s.rt.spans, s.recordedSpan)
+}
+
+func TestTracer(t *testing.T) {
+	for _, fs := range Fss {
+		if _, ok := fs.(*cephfs.Fs); ok {
+			testTracer(t)
+		}
+	}
+}
+
+func testTracer(t *testing.T) {
+	tracer := &recordingTracer{}
+	fs, err := cephfs.NewCephFS(cephfs.WithTracer(tracer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Unmount()
+
+	tDir := "/" + testDir(fs)
+	defer removeAllTestFiles(t)
+	path := filepath.Join(tDir, testName)
+	ctxFs := fs.WithContext(context.WithValue(context.Background(), parentKey{}, "request"))
+
+	f, err := ctxFs.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(f, "content"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if err := fs.Rename(path, path+".renamed"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Stat of renamed file = %v", err)
+	}
+
+	open := tracer.find("open")
+	if open == nil || open.parent != "request" || open.attrs[cephfs.AttrPath] != path {
+		t.Errorf("open span = %+v, want child of the ContextFs context", open)
+	}
+	if open != nil && open.attrs[cephfs.AttrFlags] != int64(os.O_RDWR|os.O_CREATE) {
+		t.Errorf("open span flags = %v", open.attrs[cephfs.AttrFlags])
+	}
+	if write := tracer.find("write"); write == nil || write.parent != "request" || write.attrs[cephfs.AttrBytes] != int64(7) {
+		t.Errorf("write span = %+v", write)
+	}
+	if rename := tracer.find("rename"); rename == nil || rename.parent != nil || rename.attrs[cephfs.AttrNewPath] != path+".renamed" {
+		t.Errorf("rename span = %+v, want a root span", rename)
+	}
+	stat := tracer.find("stat")
+	if stat == nil || stat.attrs[cephfs.AttrErrno] != "ENOENT" || !errors.Is(stat.err, os.ErrNotExist) {
+		t.Errorf("stat span = %+v, want ENOENT", stat)
+	}
+}
@@ -0,0 +1,272 @@
+//go:build cgo && !nocephfs
+
+package cephfs
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	gocephfs "github.com/ceph/go-ceph/cephfs"
+)
+
+const (
+	trashPathXattr = "user.trash.path"
+	trashTimeXattr = "user.trash.time"
+
+	trashDateLayout = "2006-01-02"
+)
+
+// trashCopy is how entries that cannot be renamed are copied into and out
+// of the trash, keeping all the metadata CopyTree can.
+var trashCopy = &CopyOptions{
+	PreserveMode:   true,
+	PreserveOwner:  true,
+	PreserveTimes:  true,
+	PreserveXattrs: true,
+	PreserveLayout: true,
+}
+
+// Remove moves the file or empty directory at path into the trash.
+func (tfs *TrashFs) Remove(path string) error {
+	return tfs.trash("remove", path, false)
+}
+
+// RemoveAll moves path and everything in it into the trash. Like
+// Fs.RemoveAll it does not fail if path does not exist.
+func (tfs *TrashFs) RemoveAll(path string) error {
+	return tfs.trash("removeall", path, true)
+}
+
+func (tfs *TrashFs) trash(op, path string, all bool) (err error) {
+	mount := tfs.fs.getMount()
+	defer tfs.fs.detectEviction(mount, &err)
+
+	if path, _, err = normPath(op, path); err != nil {
+		if all {
+			// like RemoveAll, do nothing for an empty path
+			return nil
+		}
+		return err
+	}
+	path = absPath(mount, path)
+	if path == "/" || strings.HasPrefix(tfs.root, path+"/") {
+		return &os.PathError{Op: op, Path: path, Err: syscall.EBUSY}
+	}
+	if tfs.inTrash(path) {
+		if all {
+			return tfs.fs.RemoveAll(path)
+		}
+		return tfs.fs.Remove(path)
+	}
+
+	stat, err := mount.Statx(path, gocephfs.StatxMode, gocephfs.AtSymlinkNofollow)
+	if err != nil {
+		if all && errors.Is(err, gocephfs.ErrNotExist) {
+			return nil
+		}
+		return pathErr(op, path, err)
+	}
+	isDir := toFileMode(stat.Mode).IsDir()
+	if isDir && !all {
+		for _, err := range tfs.fs.ReadDirNamesSeq(path) {
+			if err != nil {
+				return err
+			}
+			return &os.PathError{Op: op, Path: path, Err: syscall.ENOTEMPTY}
+		}
+	}
+
+	now := time.Now()
+	container, err := tfs.newContainer(mount, now)
+	if err != nil {
+		return &os.PathError{Op: op, Path: path, Err: err}
+	}
+	if err := mount.SetXattr(container, trashPathXattr, []byte(path), gocephfs.XattrDefault); err != nil {
+		mount.RemoveDir(container)
+		return fmt.Errorf("failed to set %s on %s: %w", trashPathXattr, container, err)
+	}
+	deletedAt := []byte(now.UTC().Format(time.RFC3339Nano))
+	if err := mount.SetXattr(container, trashTimeXattr, deletedAt, gocephfs.XattrDefault); err != nil {
+		mount.RemoveDir(container)
+		return fmt.Errorf("failed to set %s on %s: %w", trashTimeXattr, container, err)
+	}
+
+	if err := tfs.fs.MoveAcross(path, container+"/"+filepath.Base(path), trashCopy); err != nil {
+		// fails if the copy of a cross-realm move was made, keeping it
+		mount.RemoveDir(container)
+		return err
+	}
+	return nil
+}
+
+// newContainer creates the directory a removed entry is moved into. The
+// trash is shared by all users of the filesystem, so like /tmp it is world
+// writable with the sticky bit set.
+func (tfs *TrashFs) newContainer(mount *gocephfs.MountInfo, now time.Time) (string, error) {
+	dateDir := tfs.root + "/" + now.Format(trashDateLayout)
+	if err := mount.MakeDirs(dateDir, 0o1777); err != nil && !errors.Is(convertErr(err), os.ErrExist) {
+		return "", fmt.Errorf("failed to create trash directory %s: %w", dateDir, err)
+	}
+	for {
+		id := strconv.FormatInt(now.UnixNano(), 36) + "-" + strconv.FormatUint(rand.Uint64(), 36)
+		container := dateDir + "/" + id
+		err := mount.MakeDir(container, 0o700)
+		if err == nil {
+			return container, nil
+		}
+		if !errors.Is(convertErr(err), os.ErrExist) {
+			return "", fmt.Errorf("failed to create trash entry %s: %w", container, err)
+		}
+	}
+}
+
+func (tfs *TrashFs) inTrash(path string) bool {
+	return path == tfs.root || strings.HasPrefix(path, tfs.root+"/")
+}
+
+// ListTrash returns the entries in the trash, oldest first.
+func (tfs *TrashFs) ListTrash() (_ []TrashEntry, err error) {
+	mount := tfs.fs.getMount()
+	defer tfs.fs.detectEviction(mount, &err)
+
+	var entries []TrashEntry
+	for dateDir, err := range tfs.fs.ReadDirNamesSeq(tfs.root) {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		if !dateDir.IsDir() {
+			continue
+		}
+		for container, err := range tfs.fs.ReadDirNamesSeq(dateDir.Path()) {
+			if err != nil {
+				return nil, err
+			}
+			entry, err := readTrashEntry(mount, container.Path())
+			if errors.Is(err, ErrNotInTrash) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].DeletedAt.Before(entries[j].DeletedAt)
+	})
+	return entries, nil
+}
+
+// readTrashEntry reads the entry in the trash container dir.
+func readTrashEntry(mount *gocephfs.MountInfo, container string) (TrashEntry, error) {
+	original, err := mount.GetXattr(container, trashPathXattr)
+	if err != nil {
+		if errno, ok := errnoOf(err); ok && (errno == syscall.ENODATA || errno == syscall.ENOTDIR) {
+			return TrashEntry{}, ErrNotInTrash
+		}
+		return TrashEntry{}, fmt.Errorf("failed to get %s on %s: %w", trashPathXattr, container, err)
+	}
+	deletedAt, err := mount.GetXattr(container, trashTimeXattr)
+	if err != nil {
+		return TrashEntry{}, fmt.Errorf("failed to get %s on %s: %w", trashTimeXattr, container, err)
+	}
+
+	entry := TrashEntry{
+		Path:         container + "/" + filepath.Base(string(original)),
+		OriginalPath: string(original),
+	}
+	if entry.DeletedAt, err = time.Parse(time.RFC3339Nano, trimXattr(deletedAt)); err != nil {
+		return TrashEntry{}, fmt.Errorf("failed to parse %s of %s: %w", trashTimeXattr, container, err)
+	}
+	stat, err := mount.Statx(entry.Path, gocephfs.StatxMode, gocephfs.AtSymlinkNofollow)
+	if err != nil {
+		if errors.Is(err, gocephfs.ErrNotExist) {
+			// restored or purged but not cleaned up
+			return TrashEntry{}, ErrNotInTrash
+		}
+		return TrashEntry{}, fmt.Errorf("failed to stat %s: %w", entry.Path, err)
+	}
+	entry.IsDir = toFileMode(stat.Mode).IsDir()
+	return entry, nil
+}
+
+// Restore moves the trash entry at path, as returned by ListTrash, back to
+// where it was removed from. It fails with os.ErrExist if something has
+// been created there since, and with os.ErrNotExist if the directory the
+// entry was in is gone. The entry is moved back with RenameNoReplace, or
+// copied if it was removed from a different quota realm, and never replaces
+// what is at the original path.
+func (tfs *TrashFs) Restore(path string) (err error) {
+	mount := tfs.fs.getMount()
+	defer tfs.fs.detectEviction(mount, &err)
+
+	if path, _, err = normPath("restore", path); err != nil {
+		return err
+	}
+	path = absPath(mount, path)
+	container := filepath.Dir(path)
+	if !tfs.inTrash(container) {
+		return &os.PathError{Op: "restore", Path: path, Err: ErrNotInTrash}
+	}
+	entry, err := readTrashEntry(mount, container)
+	if err != nil {
+		return &os.PathError{Op: "restore", Path: path, Err: err}
+	}
+	if entry.Path != path {
+		return &os.PathError{Op: "restore", Path: path, Err: ErrNotInTrash}
+	}
+
+	if err := tfs.fs.moveAcross(path, entry.OriginalPath, trashCopy, tfs.fs.RenameNoReplace); err != nil {
+		return err
+	}
+	return convertErr(mount.RemoveDir(container))
+}
+
+// PurgeOlderThan deletes the entries that were removed more than age ago
+// for good and returns their paths in the trash. Date directories left
+// empty are removed as well.
+func (tfs *TrashFs) PurgeOlderThan(age time.Duration) ([]string, error) {
+	entries, err := tfs.ListTrash()
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-age)
+	var purged []string
+	var errs []error
+	for _, entry := range entries {
+		if !entry.DeletedAt.Before(cutoff) {
+			continue
+		}
+		container := filepath.Dir(entry.Path)
+		if err := tfs.fs.RemoveAll(container); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		purged = append(purged, entry.Path)
+
+		// fails while the date directory holds other entries
+		tfs.fs.Remove(filepath.Dir(container))
+	}
+	return purged, errors.Join(errs...)
+}
+
+// absPath resolves path against the working directory of mount.
+func absPath(mount *gocephfs.MountInfo, path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(mount.CurrentDir(), path)
+	}
+	return filepath.Clean(path)
+}
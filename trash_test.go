@@ -0,0 +1,163 @@
+//go:build cgo && !nocephfs
+
+package cephfs_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	gocephfs "github.com/ceph/go-ceph/cephfs"
+	cephfs "github.com/crimsonfez/afero-cephfs"
+	"github.com/spf13/afero"
+)
+
+func TestTrashFs(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		tDir := "/" + testDir(fs)
+		trash := cephfs.NewTrashFs(cfs, filepath.Join(tDir, ".trash"))
+		path := filepath.Join(tDir, testName)
+		dir := filepath.Join(tDir, "dir")
+
+		if err := afero.WriteFile(fs, path, []byte("content"), 0o640); err != nil {
+			t.Fatal(err)
+		}
+		if err := fs.MkdirAll(filepath.Join(dir, "nested"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := trash.Remove(dir); !errors.Is(err, syscall.ENOTEMPTY) {
+			t.Errorf("%v: Remove of non-empty directory = %v, want %v", fs.Name(), err, syscall.ENOTEMPTY)
+		}
+		if err := trash.Remove(path); err != nil {
+			t.Fatalf("%v: Remove failed: %v", fs.Name(), err)
+		}
+		if err := trash.RemoveAll(dir); err != nil {
+			t.Fatalf("%v: RemoveAll failed: %v", fs.Name(), err)
+		}
+		for _, p := range []string{path, dir} {
+			if _, err := fs.Stat(p); !os.IsNotExist(err) {
+				t.Errorf("%v: %s still exists after moving it to trash: %v", fs.Name(), p, err)
+			}
+		}
+
+		entries, err := trash.ListTrash()
+		if err != nil {
+			t.Fatalf("%v: ListTrash failed: %v", fs.Name(), err)
+		}
+		if len(entries) != 2 || entries[0].OriginalPath != path || entries[1].OriginalPath != dir || !entries[1].IsDir {
+			t.Fatalf("%v: ListTrash = %+v", fs.Name(), entries)
+		}
+
+		if err := trash.Restore(entries[0].Path); err != nil {
+			t.Fatalf("%v: Restore failed: %v", fs.Name(), err)
+		}
+		info, err := fs.Stat(path)
+		if err != nil {
+			t.Fatalf("%v: restored file is missing: %v", fs.Name(), err)
+		}
+		if info.Mode().Perm() != 0o640 || info.Size() != int64(len("content")) {
+			t.Errorf("%v: restored file has mode %v and size %d", fs.Name(), info.Mode(), info.Size())
+		}
+		if err := trash.Restore(path); !errors.Is(err, cephfs.ErrNotInTrash) {
+			t.Errorf("%v: Restore outside of trash = %v, want %v", fs.Name(), err, cephfs.ErrNotInTrash)
+		}
+
+		if err := fs.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := trash.Restore(entries[1].Path); !errors.Is(err, os.ErrExist) {
+			t.Errorf("%v: Restore onto existing directory = %v, want %v", fs.Name(), err, os.ErrExist)
+		}
+		if err := fs.Remove(dir); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, p := range []string{"/", tDir} {
+			if err := trash.RemoveAll(p); !errors.Is(err, syscall.EBUSY) {
+				t.Errorf("%v: RemoveAll(%s) = %v, want %v", fs.Name(), p, err, syscall.EBUSY)
+			}
+		}
+		if _, err := fs.Stat(trash.Root()); err != nil {
+			t.Errorf("%v: trash is gone after refused RemoveAll: %v", fs.Name(), err)
+		}
+
+		purged, err := trash.PurgeOlderThan(0)
+		if err != nil {
+			t.Fatalf("%v: PurgeOlderThan failed: %v", fs.Name(), err)
+		}
+		if len(purged) != 1 || purged[0] != entries[1].Path {
+			t.Errorf("%v: PurgeOlderThan purged %v, want %v", fs.Name(), purged, entries[1].Path)
+		}
+		if entries, err := trash.ListTrash(); err != nil || len(entries) != 0 {
+			t.Errorf("%v: ListTrash after purge = %+v, %v", fs.Name(), entries, err)
+		}
+	}
+}
+
+func TestTrashFsAcrossQuota(t *testing.T) {
+	defer removeAllTestFiles(t)
+	for _, fs := range Fss {
+		cfs, ok := fs.(*cephfs.Fs)
+		if !ok {
+			continue
+		}
+
+		tDir := "/" + testDir(fs)
+		trash := cephfs.NewTrashFs(cfs, filepath.Join(tDir, ".trash"))
+		realm := filepath.Join(tDir, "quota")
+		dir := filepath.Join(realm, "dir")
+
+		if err := fs.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := cfs.SetQuota(realm, 1<<30); err != nil {
+			t.Fatalf("%v: SetQuota failed: %v", fs.Name(), err)
+		}
+		file := filepath.Join(dir, "file")
+		if err := afero.WriteFile(fs, file, []byte("content"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := fs.Chown(file, 1234, 5678); err != nil {
+			t.Fatal(err)
+		}
+		if err := cfs.SetXattr(file, "user.test", []byte("value")); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := trash.RemoveAll(dir); err != nil {
+			t.Fatalf("%v: RemoveAll across quota realms failed: %v", fs.Name(), err)
+		}
+		if _, err := fs.Stat(dir); !os.IsNotExist(err) {
+			t.Errorf("%v: %s still exists after moving it to trash: %v", fs.Name(), dir, err)
+		}
+		entries, err := trash.ListTrash()
+		if err != nil || len(entries) != 1 || entries[0].OriginalPath != dir {
+			t.Fatalf("%v: ListTrash = %+v, %v", fs.Name(), entries, err)
+		}
+
+		if err := trash.Restore(entries[0].Path); err != nil {
+			t.Fatalf("%v: Restore across quota realms failed: %v", fs.Name(), err)
+		}
+		if data, err := afero.ReadFile(fs, file); err != nil || string(data) != "content" {
+			t.Errorf("%v: restored file = %q, %v", fs.Name(), data, err)
+		}
+		// the copies made into the trash and back keep the metadata
+		if info, err := fs.Stat(file); err != nil {
+			t.Errorf("%v: Stat of restored file failed: %v", fs.Name(), err)
+		} else if stat := info.Sys().(*gocephfs.CephStatx); stat.Uid != 1234 || stat.Gid != 5678 {
+			t.Errorf("%v: restored file is owned by %d:%d, want 1234:5678", fs.Name(), stat.Uid, stat.Gid)
+		}
+		if value, err := cfs.GetXattr(file, "user.test"); err != nil || string(value) != "value" {
+			t.Errorf("%v: restored file has user.test %q, %v", fs.Name(), value, err)
+		}
+	}
+}
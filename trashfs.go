@@ -0,0 +1,105 @@
+package cephfs
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// DefaultTrashDir is the directory TrashFs moves removed entries to unless
+// told otherwise.
+const DefaultTrashDir = "/.trash"
+
+// TrashFs is a view of an Fs where Remove and RemoveAll move entries into a
+// trash directory instead of deleting them. It implements afero.Fs, all
+// other operations are passed through.
+//
+// Every removed entry is moved to <trash>/<date>/<id>/<name>, where name is
+// its original base name. The <id> directory holds the original path and
+// the time of removal in the user.trash.path and user.trash.time xattrs.
+// The entry itself, with its mode, owner, times, xattrs and, for
+// directories, everything in it, is moved by a rename and stays unchanged.
+// Entries in a different quota realm than the trash cannot be renamed into
+// it and are moved with MoveAcross instead, and moved back the same way by
+// Restore. The copy keeps the mode, owner and times of files and
+// directories, and the xattrs and layout of files, see trashCopy.
+//
+// Removing anything inside the trash deletes it for good. Removing "/" or
+// any other directory holding the trash fails with syscall.EBUSY.
+type TrashFs struct {
+	fs   *Fs
+	root string
+}
+
+// TrashEntry is an entry of the trash.
+type TrashEntry struct {
+	// Path is where the entry is in the trash.
+	Path string
+	// OriginalPath is where the entry was removed from.
+	OriginalPath string
+	// DeletedAt is when the entry was removed.
+	DeletedAt time.Time
+	// IsDir is set if the entry is a directory.
+	IsDir bool
+}
+
+// NewTrashFs returns a view of fs with a trash at root, an absolute path.
+// An empty root uses DefaultTrashDir. The trash directory is created when
+// the first entry is removed.
+func NewTrashFs(fs *Fs, root string) *TrashFs {
+	if root == "" {
+		root = DefaultTrashDir
+	}
+	return &TrashFs{fs: fs, root: filepath.Clean(root)}
+}
+
+// Root returns the trash directory.
+func (tfs *TrashFs) Root() string {
+	return tfs.root
+}
+
+func (tfs *TrashFs) Create(path string) (afero.File, error) {
+	return tfs.fs.Create(path)
+}
+
+func (tfs *TrashFs) Mkdir(path string, perm os.FileMode) error {
+	return tfs.fs.Mkdir(path, perm)
+}
+
+func (tfs *TrashFs) MkdirAll(path string, perm os.FileMode) error {
+	return tfs.fs.MkdirAll(path, perm)
+}
+
+func (tfs *TrashFs) Open(path string) (afero.File, error) {
+	return tfs.fs.Open(path)
+}
+
+func (tfs *TrashFs) OpenFile(path string, flag int, perm os.FileMode) (afero.File, error) {
+	return tfs.fs.OpenFile(path, flag, perm)
+}
+
+func (tfs *TrashFs) Rename(oldPath, newPath string) error {
+	return tfs.fs.Rename(oldPath, newPath)
+}
+
+func (tfs *TrashFs) Stat(path string) (os.FileInfo, error) {
+	return tfs.fs.Stat(path)
+}
+
+func (tfs *TrashFs) Name() string {
+	return "TrashFs(" + tfs.fs.Name() + ")"
+}
+
+func (tfs *TrashFs) Chmod(path string, mode os.FileMode) error {
+	return tfs.fs.Chmod(path, mode)
+}
+
+func (tfs *TrashFs) Chown(path string, uid int, gid int) error {
+	return tfs.fs.Chown(path, uid, gid)
+}
+
+func (tfs *TrashFs) Chtimes(path string, atime time.Time, mtime time.Time) error {
+	return tfs.fs.Chtimes(path, atime, mtime)
+}